@@ -0,0 +1,307 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// Presto and Trino serialize the HyperLogLogs of approx_set with Airlift
+// (io.airlift.stats.cardinality.HyperLogLog), and they accept them in merge and
+// cardinality.
+//
+// Airlift calls registers buckets and indexes them by the highest bits of the
+// hash, so bucket b is register reverseIndex(b) as with ZetaSketch.  Unlike
+// HyperLogLog++, it sets a bucket for a hash whose bits after the index are all
+// zero, which the storage spec ignores, but only one hash in 2^(64-log2m) does.
+//
+// The sparse format is a sorted list of 32 bit entries with the highest 26 bits
+// of a hash and the number of leading zeros of the rest.  The dense format
+// packs each bucket into a 4 bit delta from a baseline and lists the buckets
+// whose deltas overflow, so wide registers cost 3 extra bytes each.
+
+const (
+	// format tags.  SPARSE_V1 is no longer supported by Airlift.
+	airliftDenseV1  = 1
+	airliftSparseV2 = 2
+	airliftDenseV2  = 3
+
+	airliftMinIndexBitLength = minimumLog2mParam
+	airliftMaxIndexBitLength = 16
+
+	// each sparse entry is a 26 bit bucket index followed by a 6 bit value.
+	airliftValueBits          = 6
+	airliftValueMask          = 1<<airliftValueBits - 1
+	airliftExtendedPrefixBits = 32 - airliftValueBits
+	airliftMaxSparseValue     = 64 - airliftExtendedPrefixBits
+
+	airliftSparseHeaderSize = 4
+	airliftDenseHeaderSize  = 3
+
+	// dense registers are stored as 4 bit deltas from the baseline.  a delta of
+	// airliftMaxDelta is increased by the value of the bucket's overflow, if any.
+	airliftMaxDelta = 1<<4 - 1
+)
+
+// AirliftHasher is a Hasher that gives values the same registers as Presto's
+// and Trino's approx_set does for bigint and varchar values.  Airlift uses the
+// same hash as Murmur3Hasher, so this is Murmur3Hasher with the bits reversed
+// to account for Airlift indexing registers by the highest bits of the hash.
+var AirliftHasher Hasher = airliftHasher{}
+
+// errAirliftInvalid is returned by FromAirlift when a sparse entry or dense
+// overflow is out of range.
+var errAirliftInvalid = errors.New("invalid Airlift HyperLogLog")
+
+type airliftHasher struct{}
+
+func (airliftHasher) Sum64(data []byte) uint64 {
+	return bits.Reverse64(murmur3Sum64(data))
+}
+
+// AirliftSettings returns the settings that FromAirlift uses for a HyperLogLog
+// with 2^indexBitLength buckets.  With AirliftHasher, AddString and AddInt64
+// set the buckets that approx_set sets for varchar and bigint values.  An
+// explicit Hll with up to 2^(indexBitLength-3) values fits in the sparse format,
+// so the explicit threshold is the point where ToAirlift switches to dense.
+func AirliftSettings(indexBitLength int) Settings {
+	return Settings{
+		Log2m:             indexBitLength,
+		Regwidth:          6,
+		ExplicitThreshold: 1 << uint(maxInt(indexBitLength-3, 0)),
+		SparseEnabled:     true,
+		Hasher:            AirliftHasher,
+	}
+}
+
+// FromAirlift converts a serialized Airlift HyperLogLog to an Hll whose log2m
+// is the index bit length.  It reads SPARSE_V2 and both versions of the dense
+// format.  Airlift no longer writes SPARSE_V1, so it isn't supported.
+//
+// Each sparse entry is added as the smallest hash with the entry's 26 bits and
+// leading zeros, so an explicit Hll counts small sets as precisely as Airlift
+// does.  The baseline and deltas of a dense HyperLogLog, plus any overflow,
+// become the register values.
+func FromAirlift(data []byte) (Hll, error) {
+
+	if len(data) < 2 {
+		return Hll{}, ErrInsufficientBytes
+	}
+
+	indexBitLength := int(data[1])
+	if indexBitLength < airliftMinIndexBitLength || indexBitLength > airliftMaxIndexBitLength {
+		return Hll{}, fmt.Errorf("unsupported Airlift HyperLogLog index bit length: %d", indexBitLength)
+	}
+
+	settings, err := AirliftSettings(indexBitLength).toInternal()
+	if err != nil {
+		return Hll{}, err
+	}
+
+	h := Hll{settings: settings}
+
+	switch data[0] {
+	case airliftSparseV2:
+		err = h.readAirliftSparse(data)
+	case airliftDenseV1, airliftDenseV2:
+		err = h.readAirliftDense(data)
+	default:
+		err = fmt.Errorf("unsupported Airlift HyperLogLog format: %d", data[0])
+	}
+	if err != nil {
+		return Hll{}, err
+	}
+
+	return h, nil
+}
+
+// readAirliftSparse adds the hash of each sparse entry to the Hll.
+func (h *Hll) readAirliftSparse(data []byte) error {
+
+	if len(data) < airliftSparseHeaderSize {
+		return ErrInsufficientBytes
+	}
+
+	numberOfEntries := int(binary.LittleEndian.Uint16(data[2:]))
+	if len(data) != airliftSparseHeaderSize+4*numberOfEntries {
+		return ErrInsufficientBytes
+	}
+
+	for i := 0; i < numberOfEntries; i++ {
+		entry := binary.LittleEndian.Uint32(data[airliftSparseHeaderSize+4*i:])
+
+		value := int(entry & airliftValueMask)
+		if value > airliftMaxSparseValue {
+			return errAirliftInvalid
+		}
+
+		// the smallest hash with the entry's bucket and leading zeros.
+		hash := uint64(entry>>airliftValueBits) << (64 - airliftExtendedPrefixBits)
+		if value < airliftMaxSparseValue {
+			hash |= 1 << uint(airliftMaxSparseValue-1-value)
+		}
+
+		h.AddRaw(bits.Reverse64(hash))
+	}
+
+	return nil
+}
+
+// readAirliftDense sets the registers from the deltas and overflows of either
+// version of the dense format.  Version 1 has at most one overflow, and its
+// bucket is negative when there is none.
+func (h *Hll) readAirliftDense(data []byte) error {
+
+	indexBitLength := h.settings.log2m
+	numberOfBuckets := 1 << uint(indexBitLength)
+
+	deltasEnd := airliftDenseHeaderSize + numberOfBuckets/2
+	if len(data) < deltasEnd+2 {
+		return ErrInsufficientBytes
+	}
+
+	baseline := data[2]
+	deltas := data[airliftDenseHeaderSize:deltasEnd]
+	tail := data[deltasEnd:]
+
+	overflows := make(map[int]byte)
+
+	if data[0] == airliftDenseV1 {
+		if len(tail) != 3 {
+			return ErrInsufficientBytes
+		}
+		bucket, value := int(int16(binary.LittleEndian.Uint16(tail))), int8(tail[2])
+		if bucket >= 0 && value > 0 {
+			if bucket >= numberOfBuckets {
+				return errAirliftInvalid
+			}
+			overflows[bucket] = byte(value)
+		}
+	} else {
+		count := int(binary.LittleEndian.Uint16(tail))
+		if len(tail) != 2+3*count {
+			return ErrInsufficientBytes
+		}
+		for i := 0; i < count; i++ {
+			bucket := int(binary.LittleEndian.Uint16(tail[2+2*i:]))
+			value := int8(tail[2+2*count+i])
+			if bucket >= numberOfBuckets || value <= 0 {
+				return errAirliftInvalid
+			}
+			// like Airlift, the first overflow of a bucket is used.
+			if _, ok := overflows[bucket]; !ok {
+				overflows[bucket] = byte(value)
+			}
+		}
+	}
+
+	for bucket := 0; bucket < numberOfBuckets; bucket++ {
+		// even buckets are in the high nibble.
+		delta := deltas[bucket/2] >> (4 * uint(1-bucket%2)) & airliftMaxDelta
+		if delta == airliftMaxDelta {
+			delta += overflows[bucket]
+		}
+
+		value := uint64(baseline) + uint64(delta)
+		if value > h.settings.regwidthMask {
+			return errAirliftInvalid
+		}
+		h.setRegister(reverseIndex(bucket, indexBitLength), byte(value))
+	}
+
+	if h.storage != nil && h.storage.overCapacity(h.settings) {
+		h.upgrade()
+	}
+
+	return nil
+}
+
+// ToAirlift converts the Hll to a serialized Airlift HyperLogLog that Presto and
+// Trino accept in merge and cardinality.  The index bit length is the Hll's
+// log2m, which must be between 4 and 16, and merge requires it to match the
+// HyperLogLogs that it's combined with.
+//
+// An explicit Hll becomes a sparse HyperLogLog with the highest 26 bits of its
+// reversed values as long as that's no larger than the dense format, which
+// holds the Hll's registers.  Register values that don't fit in 6 bits are
+// clamped.
+func (h *Hll) ToAirlift() ([]byte, error) {
+
+	h.initOrPanic()
+
+	indexBitLength := h.settings.log2m
+	if indexBitLength > airliftMaxIndexBitLength {
+		return nil, fmt.Errorf("cannot convert Log2m %d to Airlift HyperLogLog.  Allows at most %d", indexBitLength, airliftMaxIndexBitLength)
+	}
+
+	numberOfBuckets := 1 << uint(indexBitLength)
+
+	if explicit, ok := h.storage.(explicitStorage); ok || h.storage == nil {
+		entries := airliftSparseEntries(explicit)
+		if airliftSparseHeaderSize+4*len(entries) <= airliftDenseHeaderSize+numberOfBuckets/2+2 {
+			data := []byte{airliftSparseV2, byte(indexBitLength), 0, 0}
+			binary.LittleEndian.PutUint16(data[2:], uint16(len(entries)))
+			for _, entry := range entries {
+				data = appendUint32LE(data, entry)
+			}
+			return data, nil
+		}
+	}
+
+	registers, _ := h.convertedRegisters(indexBitLength, 6)
+
+	baseline := byte(0)
+	for i, value := range registers {
+		if i == 0 || value < baseline {
+			baseline = value
+		}
+	}
+
+	data := make([]byte, airliftDenseHeaderSize+numberOfBuckets/2+2)
+	data[0], data[1], data[2] = airliftDenseV2, byte(indexBitLength), baseline
+
+	// overflows are written in order of their buckets.  Airlift writes them in
+	// the order they occurred, but readers look up each bucket, so the order
+	// doesn't matter to them.
+	var overflowValues []byte
+	for bucket := 0; bucket < numberOfBuckets; bucket++ {
+		delta := registers[reverseIndex(bucket, indexBitLength)] - baseline
+		if delta > airliftMaxDelta {
+			data = append(data, 0, 0)
+			binary.LittleEndian.PutUint16(data[len(data)-2:], uint16(bucket))
+			overflowValues = append(overflowValues, delta-airliftMaxDelta)
+			delta = airliftMaxDelta
+		}
+		data[airliftDenseHeaderSize+bucket/2] |= delta << (4 * uint(1-bucket%2))
+	}
+
+	binary.LittleEndian.PutUint16(data[airliftDenseHeaderSize+numberOfBuckets/2:], uint16(len(overflowValues)))
+
+	return append(data, overflowValues...), nil
+}
+
+// airliftSparseEntries returns the sorted sparse entries of the reversed
+// explicit values.  Like Airlift, only the largest value is kept for each
+// bucket.
+func airliftSparseEntries(explicit explicitStorage) []uint32 {
+
+	buckets := make(map[uint32]uint32, len(explicit))
+	for raw := range explicit {
+		hash := bits.Reverse64(raw)
+		bucket := uint32(hash >> (64 - airliftExtendedPrefixBits))
+		value := uint32(bits.LeadingZeros64(hash<<airliftExtendedPrefixBits | 1<<(airliftExtendedPrefixBits-1)))
+		if value >= buckets[bucket] {
+			buckets[bucket] = value
+		}
+	}
+
+	entries := make([]uint32, 0, len(buckets))
+	for bucket, value := range buckets {
+		entries = append(entries, bucket<<airliftValueBits|value)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+
+	return entries
+}
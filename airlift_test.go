@@ -0,0 +1,215 @@
+package hll
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// airliftDense returns a version 2 dense HyperLogLog with the index bit length
+// and baseline, zero deltas, and no overflows.
+func airliftDense(indexBitLength int, baseline byte) []byte {
+	data := make([]byte, airliftDenseHeaderSize+1<<uint(indexBitLength)/2+2)
+	data[0], data[1], data[2] = airliftDenseV2, byte(indexBitLength), baseline
+	return data
+}
+
+//go:generate go run gen_airlift.go
+
+// Test_AirliftFixtures runs through the HyperLogLogs in
+// testdata/airlift/sketches.csv.gz.  Each line contains the index bit length,
+// the number of values, and the hex of the HyperLogLog after adding the strings
+// of the numbers from 0 up to the count.  The HyperLogLogs are generated by
+// gen_airlift.go, a port of the sparse and version 2 dense formats of
+// io.airlift.stats.cardinality.HyperLogLog, and the larger ones have overflows.
+func Test_AirliftFixtures(t *testing.T) {
+
+	readCSV(t, "testdata/airlift/sketches.csv.gz", func(parts []string, lineNo int) {
+
+		require.Equal(t, 3, len(parts), "required 3 columns at line %d", lineNo)
+		require.True(t, strings.HasPrefix(parts[2], `\x`), "missing \\x at line %d", lineNo)
+
+		indexBitLength, err := strconv.Atoi(parts[0])
+		require.NoError(t, err, "invalid index bit length at line %d", lineNo)
+		count, err := strconv.Atoi(parts[1])
+		require.NoError(t, err, "invalid count at line %d", lineNo)
+		expected, err := hex.DecodeString(parts[2][2:])
+		require.NoError(t, err, "invalid hex at line %d", lineNo)
+
+		h := newHll(t, AirliftSettings(indexBitLength))
+		for i := 0; i < count; i++ {
+			h.AddString(strconv.Itoa(i))
+		}
+
+		actual, err := h.ToAirlift()
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, "incorrect HyperLogLog at line %d", lineNo)
+
+		decoded, err := FromAirlift(expected)
+		require.NoError(t, err, "failed to convert line %d", lineNo)
+		require.Equal(t, registerValues(h), registerValues(decoded), "incorrect registers at line %d", lineNo)
+
+		actual, err = decoded.ToAirlift()
+		require.NoError(t, err)
+		require.Equal(t, expected, actual, "incorrect round trip at line %d", lineNo)
+	})
+}
+
+func Test_AirliftHasher(t *testing.T) {
+
+	// Airlift hashes a long as its 8 little endian bytes, which is what
+	// AddInt64 hashes.
+	for _, value := range []uint64{0, 1, 42, 1 << 63} {
+		h := newHll(t, AirliftSettings(11))
+		h.AddUint64(value)
+
+		expected := newHll(t, AirliftSettings(11))
+		expected.AddRaw(bits.Reverse64(murmur3Sum64([]byte{
+			byte(value), byte(value >> 8), byte(value >> 16), byte(value >> 24),
+			byte(value >> 32), byte(value >> 40), byte(value >> 48), byte(value >> 56),
+		})))
+
+		assert.Equal(t, expected.storage, h.storage, "%d", value)
+	}
+
+	assert.Equal(t, bits.Reverse64(Murmur3Hasher.Sum64([]byte("hello"))), AirliftHasher.Sum64([]byte("hello")))
+}
+
+func Test_FromAirlift(t *testing.T) {
+
+	// sparse: bucket 3 with 5 leading zeros and bucket 2^25 with none.
+	sparse := []byte{airliftSparseV2, 11, 2, 0, 0xc5, 0, 0, 0, 0, 0, 0, 0x80}
+
+	h, err := FromAirlift(sparse)
+	require.NoError(t, err)
+	assert.Equal(t, explicitStorage{
+		bits.Reverse64(3<<38 | 1<<32): {},
+		bits.Reverse64(1<<63 | 1<<37): {},
+	}, h.storage)
+
+	actual, err := h.ToAirlift()
+	require.NoError(t, err)
+	assert.Equal(t, sparse, actual)
+
+	// dense: bucket 0 is in the high nibble of the first byte, and bucket 5
+	// overflows by 20 beyond the baseline of 2 and the maximum delta of 15.
+	dense := airliftDense(4, 2)
+	dense[3], dense[5] = 0x31, 0x0f
+	dense = append(dense, 5, 0, 20)
+	dense[airliftDenseHeaderSize+8] = 1
+
+	h, err = FromAirlift(dense)
+	require.NoError(t, err)
+	values := registerValues(h)
+	for bucket, expected := range []byte{5, 3, 2, 2, 2, 37, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2} {
+		assert.Equal(t, expected, values[reverseIndex(bucket, 4)], "bucket %d", bucket)
+	}
+
+	actual, err = h.ToAirlift()
+	require.NoError(t, err)
+	assert.Equal(t, dense, actual)
+
+	// version 1 has a single overflow, which is absent when the bucket is
+	// negative.
+	v1 := airliftDense(4, 2)
+	v1[0], v1[5] = airliftDenseV1, 0x0f
+	v1 = append(v1[:len(v1)-2], 5, 0, 20)
+
+	h, err = FromAirlift(v1)
+	require.NoError(t, err)
+	actual, err = h.ToAirlift()
+	require.NoError(t, err)
+	expected := airliftDense(4, 2)
+	expected[5] = 0x0f
+	expected[airliftDenseHeaderSize+8] = 1
+	expected = append(expected, 5, 0, 20)
+	assert.Equal(t, expected, actual)
+
+	v1 = append(v1[:len(v1)-3], 0xff, 0xff, 20)
+	h, err = FromAirlift(v1)
+	require.NoError(t, err)
+	assert.Equal(t, byte(17), registerValues(h)[reverseIndex(5, 4)])
+}
+
+func Test_ToAirlift(t *testing.T) {
+
+	// an empty Hll is an empty sparse HyperLogLog like a new one in Airlift.
+	h := newHll(t, AirliftSettings(12))
+	actual, err := h.ToAirlift()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{airliftSparseV2, 12, 0, 0}, actual)
+
+	// the registers are converted regardless of the storage.  larger explicit
+	// Hlls fall back to the dense format.
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = AirliftHasher.Sum64([]byte(strconv.Itoa(i)))
+	}
+
+	expected := newHll(t, AirliftSettings(11))
+	expected.AddRawBatch(values)
+	expectedBytes, err := expected.ToAirlift()
+	require.NoError(t, err)
+	require.Equal(t, byte(airliftDenseV2), expectedBytes[0])
+
+	for _, settings := range []Settings{
+		{Log2m: 11, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false},
+		{Log2m: 11, Regwidth: 8, ExplicitThreshold: 0, SparseEnabled: true},
+		{Log2m: 11, Regwidth: 6, ExplicitThreshold: 1024, SparseEnabled: true},
+	} {
+		h := newHll(t, settings)
+		h.AddRawBatch(values)
+
+		actual, err := h.ToAirlift()
+		require.NoError(t, err)
+		assert.Equal(t, expectedBytes, actual, "%+v", settings)
+	}
+
+	large := newHll(t, Settings{Log2m: 17, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	_, err = large.ToAirlift()
+	assert.Error(t, err)
+}
+
+func Test_FromAirlift_Errors(t *testing.T) {
+
+	dense := airliftDense(4, 0)
+
+	tests := []struct {
+		label string
+		bytes []byte
+		err   error
+	}{
+		{"short header", []byte{airliftSparseV2}, ErrInsufficientBytes},
+		{"sparse truncated", []byte{airliftSparseV2, 11, 1, 0, 0, 0, 0}, ErrInsufficientBytes},
+		{"sparse trailing", []byte{airliftSparseV2, 11, 0, 0, 0}, ErrInsufficientBytes},
+		{"sparse value", []byte{airliftSparseV2, 11, 1, 0, 39, 0, 0, 0}, errAirliftInvalid},
+		{"dense truncated", dense[:len(dense)-1], ErrInsufficientBytes},
+		{"dense trailing", append(dense, 0), ErrInsufficientBytes},
+		{"overflow truncated", append(append([]byte(nil), dense[:len(dense)-2]...), 1, 0, 0, 0), ErrInsufficientBytes},
+		{"overflow bucket", append(append([]byte(nil), dense[:len(dense)-2]...), 1, 0, 16, 0, 1), errAirliftInvalid},
+		{"overflow value", append(append([]byte(nil), dense[:len(dense)-2]...), 1, 0, 3, 0, 0), errAirliftInvalid},
+		{"wide register", airliftDense(4, 64), errAirliftInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			_, err := FromAirlift(tt.bytes)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	for _, bytes := range [][]byte{
+		{0, 11, 0, 0},              // SPARSE_V1
+		{4, 11, 0, 0},              // unknown format
+		{airliftSparseV2, 3, 0, 0}, // too few buckets
+		{airliftSparseV2, 17, 0, 0},
+	} {
+		_, err := FromAirlift(bytes)
+		assert.Error(t, err, "%x", bytes)
+	}
+}
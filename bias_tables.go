@@ -0,0 +1,491 @@
+// Code generated by gen_bias.go; DO NOT EDIT.
+
+package hll
+
+// hllPlusPlusRawEstimates contains the mean raw estimates for log2m 4 through
+// 18, indexed by log2m - 4.  Each row is sorted in ascending order.
+var hllPlusPlusRawEstimates = [][]float64{
+	// log2m = 4
+	{
+		10.768, 11.23787, 11.72275, 12.22332, 12.73942, 13.27098, 13.81898, 14.38256,
+		14.96183, 15.55672, 16.16774, 16.79463, 17.43665, 18.09382, 18.76638, 19.45448,
+		20.15654, 20.87402, 21.60531, 22.35154, 23.11089, 23.88511, 24.67109, 25.4695,
+		26.2795, 27.10233, 27.93433, 28.77846, 29.6331, 30.49731, 31.37203, 32.25507,
+		33.14751, 34.04852, 34.95453, 35.87268, 36.79237, 37.72001, 38.654, 39.5883,
+		40.53101, 41.4787, 42.43023, 43.38581, 44.34502, 45.31086, 46.28195, 47.25348,
+		48.22342, 49.19879, 50.17744, 51.15951, 52.14007, 53.12557, 54.11377, 55.10291,
+		56.08949, 57.07811, 58.07285, 59.06501, 60.0538, 61.04615, 62.0419, 63.03671,
+		64.03345, 65.02492, 66.02399, 67.02027, 68.01741, 69.01675, 70.01274, 71.01153,
+		72.00878, 73.00732, 74.00575, 75.00565, 76.00295, 77.00194, 77.99773, 78.99492,
+		79.99274, 80.99162, 81.99267, 82.99234, 83.98848, 84.99234, 85.99474, 86.99546,
+		87.99738,
+	},
+	// log2m = 5
+	{
+		22.304, 22.77926, 23.7524, 24.75481, 25.7865, 26.31414, 27.39143, 28.49908,
+		29.6371, 30.8049, 31.40074, 32.61303, 33.85466, 35.12757, 35.77456, 37.08859,
+		38.43072, 39.80342, 41.20434, 41.9137, 43.35475, 44.82074, 46.3123, 47.06919,
+		48.60043, 50.15536, 51.73229, 53.33521, 54.14481, 55.77827, 57.43387, 59.1087,
+		59.95496, 61.66066, 63.38487, 65.12623, 66.88426, 67.7707, 69.54878, 71.34363,
+		73.15665, 74.06395, 75.89098, 77.73309, 79.58233, 81.44334, 82.37819, 84.25773,
+		86.14542, 88.04208, 88.99644, 90.90974, 92.82842, 94.74754, 96.67805, 97.64305,
+		99.57564, 101.5127, 103.4603, 104.4351, 106.3985, 108.357, 110.3133, 112.2841,
+		113.2709, 115.2406, 117.2156, 119.1956, 120.1897, 122.1614, 124.1475, 126.133,
+		128.1191, 129.1078, 131.0846, 133.0809, 135.0685, 136.0606, 138.0579, 140.0511,
+		142.046, 144.0359, 145.0347, 147.0274, 149.02, 151.0115, 152.0147, 154.0151,
+		156.0192, 158.0245, 160.0209, 161.0172, 163.0179, 165.0198, 167.0111, 168.0159,
+		170.014, 172.0121, 174.0116, 176.0155,
+	},
+	// log2m = 6
+	{
+		45.376, 46.82069, 48.7991, 50.32151, 52.40212, 54.00059, 56.18221, 57.85811,
+		60.14348, 62.48804, 64.28518, 66.73181, 68.60539, 71.14954, 73.09777, 75.74656,
+		77.76795, 80.51277, 83.31058, 85.43804, 88.32444, 90.52101, 93.49569, 95.75951,
+		98.82068, 101.1444, 104.2888, 107.4732, 109.8895, 113.1598, 115.6337, 118.9607,
+		121.4871, 124.8823, 127.4465, 130.9098, 134.3974, 137.0426, 140.5804, 143.2526,
+		146.8559, 149.5657, 153.1987, 155.9303, 159.6158, 163.322, 166.109, 169.846,
+		172.6636, 176.4338, 179.2765, 183.07, 185.9207, 189.7522, 193.5957, 196.4877,
+		200.3501, 203.2583, 207.1419, 210.0577, 213.9326, 216.8652, 220.7834, 224.708,
+		227.65, 231.5763, 234.5339, 238.4743, 241.4456, 245.3973, 248.3674, 252.3173,
+		256.2894, 259.2442, 263.222, 266.204, 270.1795, 273.1705, 277.1446, 280.13,
+		284.091, 288.0675, 291.0403, 295.0116, 297.9927, 301.9826, 304.9666, 308.9579,
+		311.9569, 315.9494, 319.9392, 322.9431, 326.9415, 329.9352, 333.9286, 336.9206,
+		340.9201, 343.915, 347.9123, 351.9054,
+	},
+	// log2m = 7
+	{
+		91.55462, 94.94845, 98.43362, 102.0069, 105.6694, 109.4205, 113.2581, 117.1849,
+		121.2059, 125.9065, 130.1063, 134.4038, 138.7873, 143.2472, 147.7992, 152.4352,
+		157.1553, 161.955, 167.5425, 172.5193, 177.5705, 182.6916, 187.8987, 193.1776,
+		198.5281, 203.9449, 209.4398, 215.797, 221.4266, 227.1268, 232.8784, 238.6955,
+		244.5594, 250.483, 256.4782, 262.5224, 269.4889, 275.6451, 281.8323, 288.0757,
+		294.3505, 300.6578, 307.0049, 313.3729, 319.7929, 327.1709, 333.6537, 340.1883,
+		346.7521, 353.3495, 359.9674, 366.6323, 373.2903, 379.9754, 387.6146, 394.3488,
+		401.1058, 407.871, 414.6419, 421.4319, 428.2347, 435.0642, 441.9062, 449.7382,
+		456.6191, 463.5035, 470.3946, 477.2935, 484.2136, 491.1146, 497.9747, 504.8904,
+		512.8097, 519.7492, 526.7092, 533.6544, 540.5862, 547.5101, 554.4681, 561.4199,
+		568.4129, 576.3846, 583.3635, 590.3574, 597.3373, 604.3085, 611.2614, 618.2665,
+		625.2517, 632.2363, 640.203, 647.1803, 654.1581, 661.1703, 668.1369, 675.1006,
+		682.0941, 689.1045, 696.0951, 704.0782,
+	},
+	// log2m = 8
+	{
+		183.8778, 190.682, 197.664, 204.825, 212.1651, 220.2262, 227.9346, 235.8154,
+		243.8697, 252.699, 261.1337, 269.7358, 278.5089, 287.4464, 297.201, 306.4896,
+		315.9401, 325.5488, 336.0396, 345.9895, 356.0826, 366.3623, 376.7704, 388.0923,
+		398.8175, 409.6458, 420.6241, 432.529, 443.7797, 455.163, 466.67, 478.2851,
+		490.8508, 502.7225, 514.6593, 526.7016, 539.7559, 552.0059, 564.407, 576.8524,
+		589.3616, 602.8685, 615.584, 628.3342, 641.1629, 654.9966, 667.9667, 681.0151,
+		694.1119, 707.2464, 721.4065, 734.6928, 748.0334, 761.3921, 775.7572, 789.2447,
+		802.6884, 816.1641, 829.705, 844.2669, 857.8376, 871.4312, 885.0762, 899.7049,
+		913.4203, 927.1341, 940.8419, 954.6466, 969.4267, 983.2319, 997.0635, 1010.911,
+		1025.694, 1039.48, 1053.363, 1067.264, 1081.119, 1096.019, 1109.836, 1123.739,
+		1137.622, 1152.494, 1166.492, 1180.465, 1194.373, 1208.316, 1223.274, 1237.32,
+		1251.277, 1265.29, 1280.21, 1294.157, 1308.204, 1322.179, 1336.188, 1351.11,
+		1365.116, 1379.075, 1393.069, 1408.102,
+	},
+	// log2m = 9
+	{
+		368.529, 382.1639, 396.1371, 410.9851, 425.6849, 441.2793, 456.7035, 473.0542,
+		489.2031, 506.2901, 523.1279, 540.3312, 558.4767, 576.4054, 595.2762, 613.8768,
+		633.4793, 652.7173, 672.9691, 692.8516, 713.0612, 734.3011, 755.16, 777.0708,
+		798.4666, 820.9044, 842.9159, 865.9717, 888.4647, 911.2087, 934.9995, 958.2037,
+		982.4921, 1006.167, 1030.996, 1055.141, 1080.364, 1104.929, 1129.622, 1155.352,
+		1180.418, 1206.562, 1231.923, 1258.29, 1283.981, 1310.725, 1336.69, 1362.649,
+		1389.779, 1416.112, 1443.426, 1469.868, 1497.394, 1524.113, 1551.815, 1578.613,
+		1605.544, 1633.519, 1660.654, 1688.786, 1716.05, 1744.349, 1771.602, 1799.982,
+		1827.56, 1854.924, 1883.522, 1911.124, 1939.658, 1967.337, 1995.907, 2023.602,
+		2052.157, 2079.973, 2107.599, 2136.245, 2163.887, 2192.651, 2220.446, 2249.262,
+		2276.989, 2305.895, 2333.724, 2361.666, 2390.432, 2418.285, 2447.187, 2475.135,
+		2504.101, 2532.03, 2560.882, 2589.054, 2617.044, 2646.024, 2673.891, 2702.715,
+		2730.678, 2759.67, 2787.654, 2816.391,
+	},
+	// log2m = 10
+	{
+		737.8337, 765.0922, 793.5625, 822.7724, 852.7383, 883.4115, 914.8333, 946.979,
+		979.8453, 1013.445, 1047.152, 1082.162, 1117.915, 1154.375, 1191.509, 1229.342,
+		1267.915, 1307.055, 1346.946, 1386.702, 1427.797, 1469.517, 1511.828, 1554.764,
+		1598.299, 1642.427, 1687.045, 1732.346, 1777.283, 1823.51, 1870.293, 1917.584,
+		1965.37, 2013.604, 2062.283, 2111.337, 2160.838, 2209.932, 2260.292, 2310.954,
+		2361.879, 2413.203, 2464.827, 2516.731, 2568.943, 2621.43, 2673.174, 2726.232,
+		2779.611, 2833.127, 2886.863, 2940.898, 2994.97, 3049.336, 3103.698, 3157.526,
+		3212.334, 3267.244, 3322.506, 3377.822, 3433.079, 3488.332, 3544.14, 3599.764,
+		3654.44, 3710.277, 3766.216, 3822.131, 3878.175, 3934.396, 3990.506, 4046.779,
+		4103.177, 4158.616, 4215.156, 4271.69, 4328.361, 4384.816, 4441.608, 4498.269,
+		4554.905, 4611.567, 4667.525, 4724.272, 4780.861, 4837.469, 4894.127, 4951.045,
+		5007.824, 5064.452, 5121.19, 5177.003, 5234.063, 5290.982, 5347.967, 5404.978,
+		5462.151, 5518.778, 5576.143, 5633.356,
+	},
+	// log2m = 11
+	{
+		1476.445, 1531.515, 1588.48, 1646.927, 1706.777, 1767.577, 1830.475, 1894.755,
+		1960.503, 2027.749, 2095.801, 2165.866, 2237.384, 2310.379, 2384.082, 2459.949,
+		2536.961, 2615.34, 2694.954, 2775.204, 2857.339, 2940.886, 3025.625, 3111.038,
+		3198.28, 3286.553, 3375.939, 3466.521, 3557.339, 3649.888, 3743.554, 3838.419,
+		3933.024, 4029.327, 4126.704, 4224.7, 4323.827, 4422.756, 4523.391, 4624.386,
+		4726.316, 4828.033, 4931.296, 5035.36, 5140.051, 5245.176, 5349.661, 5455.798,
+		5562.404, 5668.928, 5775.236, 5883.211, 5990.718, 6099.164, 6208.452, 6316.687,
+		6426.204, 6536.318, 6646.893, 6756.035, 6866.432, 6977.004, 7088.258, 7199.424,
+		7309.903, 7421.382, 7533.349, 7645.742, 7756.703, 7869.339, 7981.831, 8094.013,
+		8206.638, 8318.286, 8431.272, 8544.552, 8657.613, 8770.02, 8883.153, 8996.359,
+		9109.414, 9222.625, 9335.263, 9448.867, 9562.458, 9676.296, 9788.535, 9902.075,
+		10015.91, 10129.81, 10243.39, 10356.02, 10470.35, 10583.92, 10698.18, 10810.27,
+		10923.65, 11037.46, 11151.64, 11265.44,
+	},
+	// log2m = 12
+	{
+		2953.667, 3064.267, 3178.309, 3294.712, 3414.603, 3536.934, 3662.618, 3790.711,
+		3922.219, 4056.742, 4193.563, 4333.677, 4476.134, 4622.11, 4770.108, 4921.535,
+		5074.957, 5231.469, 5390.903, 5552.166, 5716.628, 5882.945, 6052.62, 6223.636,
+		6397.88, 6573.671, 6752.287, 6933.183, 7115.268, 7300.762, 7486.924, 7676.366,
+		7866.222, 8058.597, 8252.839, 8448.102, 8645.669, 8844.593, 9044.62, 9246.175,
+		9449.965, 9653.821, 9860.554, 10067.15, 10276.32, 10486.6, 10696.77, 10909.19,
+		11121.21, 11334.99, 11549.3, 11764.82, 11980.02, 12196.7, 12415.01, 12633.63,
+		12853.74, 13073.06, 13293.7, 13513.38, 13733.76, 13954.49, 14177.95, 14400.78,
+		14621.98, 14845.76, 15068.54, 15292.22, 15515.69, 15740.41, 15964.34, 16190.07,
+		16415.11, 16640.52, 16866.55, 17091.86, 17317.71, 17542.12, 17768.71, 17994.65,
+		18221.2, 18448.06, 18674.05, 18899.83, 19126.16, 19353.26, 19579.83, 19807.31,
+		20033.13, 20260.08, 20486.71, 20712.54, 20938.8, 21164.76, 21392.1, 21619.11,
+		21846.55, 22072.47, 22300.74, 22528.52,
+	},
+	// log2m = 13
+	{
+		5908.111, 6129.723, 6357.11, 6590.357, 6829.454, 7074.488, 7325.339, 7581.89,
+		7844.308, 8113.228, 8387.026, 8666.726, 8952.164, 9243.109, 9539.552, 9841.159,
+		10148.46, 10461.51, 10780.35, 11103.39, 11430.85, 11764.21, 12102.45, 12445.29,
+		12793.82, 13145.58, 13502.2, 13865.07, 14230.09, 14599.35, 14972.21, 15349.79,
+		15731.31, 16116.95, 16505.44, 16896.58, 17291.05, 17689.67, 18091.06, 18494.7,
+		18900.3, 19309.84, 19721.71, 20136.16, 20552.21, 20972.18, 21392.5, 21816.55,
+		22241.38, 22667.82, 23096.6, 23526.24, 23957.62, 24391.53, 24826.8, 25262.57,
+		25699.64, 26138.83, 26577.96, 27019.28, 27459.57, 27903.07, 28345.52, 28791.31,
+		29238.06, 29682.79, 30129.2, 30576.76, 31025.11, 31472.87, 31921.31, 32372.27,
+		32822.92, 33272.83, 33724.09, 34174.45, 34627.37, 35078.53, 35529.95, 35982.3,
+		36433.85, 36888.3, 37341.75, 37793.51, 38246.74, 38699.09, 39153.08, 39607.58,
+		40062.09, 40515.23, 40969.87, 41423.19, 41876.69, 42331.15, 42786.44, 43244.29,
+		43697.47, 44152.12, 44605.12, 45059.06,
+	},
+	// log2m = 14
+	{
+		11817.0, 12260.38, 12715.22, 13181.84, 13659.82, 14150.01, 14651.48, 15164.29,
+		15689.09, 16226.14, 16774.28, 17333.92, 17903.88, 18485.34, 19079.1, 19682.78,
+		20297.19, 20922.53, 21558.94, 22204.4, 22861.15, 23528.21, 24205.04, 24892.28,
+		25586.68, 26292.2, 27005.67, 27728.7, 28459.05, 29198.51, 29945.22, 30700.43,
+		31462.49, 32231.27, 33006.14, 33788.84, 34580.52, 35378.71, 36181.51, 36990.9,
+		37803.0, 38620.09, 39444.4, 40274.15, 41106.96, 41945.44, 42788.75, 43635.36,
+		44486.46, 45338.59, 46195.13, 47056.01, 47920.39, 48786.86, 49659.28, 50530.42,
+		51409.24, 52290.74, 53168.17, 54049.81, 54933.25, 55814.83, 56699.04, 57587.9,
+		58476.77, 59369.27, 60264.5, 61158.74, 62055.37, 62948.56, 63848.23, 64743.95,
+		65645.37, 66545.18, 67445.37, 68348.13, 69253.65, 70155.99, 71058.6, 71960.09,
+		72863.86, 73770.34, 74677.14, 75581.61, 76492.31, 77394.63, 78306.2, 79213.74,
+		80122.36, 81026.68, 81937.08, 82846.33, 83756.95, 84660.71, 85566.47, 86476.79,
+		87387.95, 88297.65, 89205.1, 90111.41,
+	},
+	// log2m = 15
+	{
+		23634.78, 24521.25, 25430.93, 26364.5, 27321.5, 28301.38, 29304.4, 30331.91,
+		31381.58, 32455.29, 33550.92, 34668.61, 35809.37, 36973.37, 38161.4, 39369.12,
+		40598.2, 41847.5, 43119.3, 44411.6, 45722.88, 47054.49, 48406.93, 49780.29,
+		51170.47, 52580.86, 54007.79, 55452.12, 56912.3, 58391.17, 59883.63, 61395.36,
+		62918.15, 64456.22, 66008.74, 67575.43, 69153.4, 70742.39, 72349.26, 73966.2,
+		75597.34, 77234.8, 78882.52, 80541.16, 82205.81, 83884.55, 85566.74, 87258.46,
+		88957.06, 90662.06, 92381.87, 94098.63, 95827.37, 97557.58, 99294.2, 101034.4,
+		102780.0, 104532.1, 106293.5, 108057.8, 109826.5, 111600.2, 113373.3, 115156.7,
+		116937.2, 118722.8, 120515.8, 122304.2, 124101.6, 125891.9, 127688.4, 129482.2,
+		131284.0, 133086.1, 134892.8, 136690.5, 138491.3, 140300.9, 142112.1, 143917.2,
+		145725.7, 147538.4, 149352.3, 151169.7, 152975.3, 154787.0, 156599.9, 158415.8,
+		160228.4, 162047.9, 163863.9, 165689.3, 167498.1, 169320.8, 171131.0, 172943.0,
+		174752.1, 176578.7, 178401.5, 180215.0,
+	},
+	// log2m = 16
+	{
+		47270.34, 49043.9, 50863.66, 52730.14, 54644.1, 56604.76, 58612.56, 60666.92,
+		62767.9, 64915.57, 67107.57, 69346.3, 71626.18, 73953.67, 76328.38, 78744.61,
+		81203.54, 83707.83, 86251.56, 88836.49, 91466.54, 94134.72, 96840.98, 99583.25,
+		102364.4, 105182.7, 108038.5, 110927.7, 113857.5, 116815.4, 119807.8, 122831.7,
+		125875.8, 128955.7, 132061.2, 135199.4, 138357.9, 141539.0, 144751.1, 147982.6,
+		151231.9, 154518.3, 157821.6, 161142.6, 164484.8, 167845.8, 171218.7, 174601.8,
+		177999.4, 181419.9, 184854.6, 188293.7, 191753.3, 195226.0, 198704.3, 202194.3,
+		205689.2, 209197.3, 212711.5, 216238.5, 219781.2, 223320.1, 226870.0, 230421.3,
+		233983.0, 237545.9, 241113.4, 244677.7, 248257.0, 251833.5, 255426.3, 259020.4,
+		262628.1, 266228.9, 269828.9, 273437.3, 277048.5, 280676.2, 284284.0, 287895.5,
+		291505.3, 295132.1, 298757.9, 302384.0, 306011.1, 309645.7, 313274.9, 316898.2,
+		320522.3, 324149.5, 327760.2, 331398.4, 335034.3, 338656.1, 342294.6, 345949.3,
+		349568.7, 353193.1, 356827.4, 360487.9,
+	},
+	// log2m = 17
+	{
+		94541.46, 98088.85, 101729.7, 105465.1, 109294.1, 113214.1, 117227.9, 121334.9,
+		125535.8, 129828.4, 134213.2, 138687.9, 143254.1, 147908.4, 152654.9, 157485.8,
+		162406.4, 167411.6, 172501.2, 177669.5, 182919.0, 188254.0, 193665.6, 199152.0,
+		204724.4, 210363.4, 216079.0, 221857.1, 227696.3, 233607.0, 239587.0, 245634.4,
+		251732.1, 257894.7, 264102.5, 270364.9, 276680.9, 283049.4, 289475.1, 295935.4,
+		302447.9, 308991.8, 315590.2, 322223.4, 328889.2, 335612.6, 342356.9, 349123.7,
+		355933.8, 362772.4, 369627.0, 376507.4, 383408.3, 390348.5, 397302.6, 404277.0,
+		411275.5, 418297.9, 425325.1, 432385.4, 439455.8, 446541.9, 453631.2, 460731.6,
+		467856.2, 474967.9, 482110.3, 489247.8, 496403.3, 503571.6, 510740.0, 517942.3,
+		525137.4, 532322.8, 539520.8, 546746.8, 553958.0, 561170.4, 568403.1, 575636.2,
+		582871.0, 590124.8, 597375.2, 604626.6, 611896.6, 619153.9, 626396.2, 633659.9,
+		640918.6, 648184.9, 655434.5, 662705.6, 669955.7, 677221.1, 684493.8, 691764.4,
+		699031.1, 706288.1, 713573.0, 720869.3,
+	},
+	// log2m = 18
+	{
+		189083.7, 196178.5, 203460.5, 210929.0, 218583.3, 226424.8, 234452.6, 242666.4,
+		251068.3, 259651.3, 268416.8, 277366.3, 286502.0, 295813.6, 305305.7, 314971.2,
+		324812.6, 334825.3, 345001.7, 355345.5, 365858.3, 376526.9, 387342.0, 398316.8,
+		409444.5, 420724.5, 432142.8, 443697.3, 455395.5, 467220.3, 479165.7, 491250.9,
+		503442.2, 515758.9, 528183.5, 540719.6, 553362.0, 566091.0, 578929.3, 591868.0,
+		604887.2, 617991.6, 631168.4, 644441.4, 657774.0, 671183.7, 684669.4, 698230.5,
+		711841.7, 725520.4, 739248.8, 753038.6, 766882.4, 780737.9, 794662.9, 808612.7,
+		822615.3, 836662.0, 850757.1, 864875.3, 879023.8, 893189.6, 907391.4, 921613.3,
+		935854.2, 950128.8, 964397.3, 978728.1, 993062.0, 1007388.0, 1021749.0, 1036157.0,
+		1050535.0, 1064933.0, 1079345.0, 1093791.0, 1108230.0, 1122696.0, 1137137.0, 1151602.0,
+		1166123.0, 1180594.0, 1195087.0, 1209564.0, 1224072.0, 1238572.0, 1253065.0, 1267568.0,
+		1282069.0, 1296599.0, 1311132.0, 1325671.0, 1340191.0, 1354727.0, 1369309.0, 1383817.0,
+		1398374.0, 1412893.0, 1427414.0, 1441962.0,
+	},
+}
+
+// hllPlusPlusBiases contains the mean bias of the raw estimate at the
+// corresponding position in hllPlusPlusRawEstimates.
+var hllPlusPlusBiases = [][]float64{
+	// log2m = 4
+	{
+		10.768, 10.23787, 9.722747, 9.223321, 8.739419, 8.270981, 7.818975, 7.382562,
+		6.961828, 6.55672, 6.167742, 5.79463, 5.436649, 5.093819, 4.766381, 4.454483,
+		4.156545, 3.874015, 3.605312, 3.351543, 3.110887, 2.885106, 2.671091, 2.469502,
+		2.279501, 2.102326, 1.934327, 1.778462, 1.633096, 1.497315, 1.372029, 1.255068,
+		1.147509, 1.048518, 0.9545305, 0.8726801, 0.7923704, 0.7200051, 0.6539979, 0.5883045,
+		0.5310119, 0.478698, 0.4302267, 0.3858115, 0.3450233, 0.3108645, 0.2819511, 0.2534798,
+		0.223421, 0.1987875, 0.177441, 0.159512, 0.1400718, 0.1255697, 0.1137686, 0.1029063,
+		0.08949195, 0.07810965, 0.07285338, 0.06501229, 0.0538002, 0.04614512, 0.04190325, 0.03671031,
+		0.03345044, 0.02491793, 0.02399353, 0.02026755, 0.01741327, 0.01674965, 0.012736, 0.01152947,
+		0.008779461, 0.007322834, 0.005751708, 0.005645484, 0.002945569, 0.001936993, -0.002274085, -0.005076153,
+		-0.007257091, -0.008384462, -0.007331142, -0.007659951, -0.01152308, -0.007664142, -0.005255496, -0.004541647,
+		-0.002621123,
+	},
+	// log2m = 5
+	{
+		22.304, 21.77926, 20.7524, 19.75481, 18.7865, 18.31414, 17.39143, 16.49908,
+		15.6371, 14.8049, 14.40074, 13.61303, 12.85466, 12.12757, 11.77456, 11.08859,
+		10.43072, 9.803418, 9.204341, 8.913702, 8.354755, 7.820741, 7.312296, 7.069192,
+		6.600429, 6.15536, 5.732293, 5.335211, 5.144814, 4.778269, 4.433865, 4.108698,
+		3.954958, 3.66066, 3.384867, 3.126228, 2.884258, 2.7707, 2.548785, 2.343625,
+		2.156654, 2.063951, 1.890977, 1.733095, 1.582329, 1.443344, 1.37819, 1.257728,
+		1.145418, 1.042077, 0.9964434, 0.909743, 0.828417, 0.7475384, 0.6780486, 0.6430451,
+		0.5756355, 0.5126643, 0.4603052, 0.4350934, 0.3985056, 0.3569948, 0.3132532, 0.2841107,
+		0.270875, 0.2405607, 0.2155679, 0.1955983, 0.1897381, 0.1614196, 0.1474541, 0.1329742,
+		0.1190536, 0.1077891, 0.08464112, 0.08087919, 0.06850568, 0.06055288, 0.05786016, 0.05106115,
+		0.04602003, 0.03594256, 0.03468634, 0.02742029, 0.0199721, 0.01154925, 0.01470355, 0.01511912,
+		0.01919272, 0.02446805, 0.02090852, 0.01719945, 0.01787133, 0.01984035, 0.01112373, 0.01587902,
+		0.0139865, 0.01212375, 0.01155135, 0.0154649,
+	},
+	// log2m = 6
+	{
+		45.376, 43.82069, 41.7991, 40.32151, 38.40212, 37.00059, 35.18221, 33.85811,
+		32.14348, 30.48804, 29.28518, 27.73181, 26.60539, 25.14954, 24.09777, 22.74656,
+		21.76795, 20.51277, 19.31058, 18.43804, 17.32444, 16.52101, 15.49569, 14.75951,
+		13.82068, 13.14442, 12.28876, 11.47322, 10.88947, 10.15978, 9.633668, 8.960719,
+		8.487085, 7.882302, 7.446513, 6.909762, 6.397427, 6.042638, 5.580444, 5.252613,
+		4.855896, 4.565706, 4.198656, 3.930322, 3.615817, 3.322006, 3.109035, 2.845978,
+		2.663636, 2.433814, 2.276482, 2.070044, 1.92067, 1.752202, 1.595661, 1.487744,
+		1.350075, 1.25827, 1.14188, 1.057699, 0.9325515, 0.8652075, 0.7834107, 0.7079923,
+		0.6499928, 0.5762751, 0.5338829, 0.474307, 0.4456113, 0.3972724, 0.3674015, 0.31731,
+		0.2893581, 0.2441889, 0.2219716, 0.2040211, 0.1795411, 0.1704999, 0.14456, 0.1299515,
+		0.09096191, 0.0674826, 0.04026203, 0.01163015, -0.00730312, -0.01741661, -0.03336288, -0.04207846,
+		-0.04311292, -0.05057301, -0.06083859, -0.05685336, -0.05849908, -0.06481226, -0.0714086, -0.07935156,
+		-0.0798782, -0.0849503, -0.08767315, -0.09457078,
+	},
+	// log2m = 7
+	{
+		91.55462, 87.94845, 84.43362, 81.00691, 77.66944, 74.42048, 71.25809, 68.18493,
+		65.20589, 61.90645, 59.10627, 56.40381, 53.78725, 51.24718, 48.79916, 46.43519,
+		44.15526, 41.95499, 39.54254, 37.51929, 35.57048, 33.69165, 31.89872, 30.17759,
+		28.5281, 26.94489, 25.43977, 23.79697, 22.4266, 21.1268, 19.87845, 18.69551,
+		17.55944, 16.48305, 15.47821, 14.52235, 13.48893, 12.64512, 11.83227, 11.0757,
+		10.35053, 9.657801, 9.004896, 8.372938, 7.7929, 7.170885, 6.653718, 6.188328,
+		5.752136, 5.349457, 4.967351, 4.63232, 4.290289, 3.975365, 3.614611, 3.348845,
+		3.105846, 2.871035, 2.641902, 2.43188, 2.23471, 2.064242, 1.906194, 1.738156,
+		1.619068, 1.503498, 1.394622, 1.293463, 1.213648, 1.114631, 0.9746842, 0.8904189,
+		0.8097315, 0.7491979, 0.7091592, 0.6543892, 0.5862116, 0.510143, 0.4680777, 0.4198501,
+		0.4129355, 0.3846136, 0.3634896, 0.3573751, 0.3372838, 0.3085314, 0.2613843, 0.2664512,
+		0.2516531, 0.2362933, 0.2030482, 0.1803451, 0.1581152, 0.1703164, 0.1368797, 0.1006018,
+		0.09407458, 0.104537, 0.09513114, 0.07822979,
+	},
+	// log2m = 8
+	{
+		183.8778, 176.682, 169.664, 162.825, 156.1651, 149.2262, 142.9346, 136.8154,
+		130.8697, 124.699, 119.1337, 113.7358, 108.5089, 103.4464, 98.20102, 93.48962,
+		88.94012, 84.54883, 80.03965, 75.98947, 72.08264, 68.36231, 64.77042, 61.09231,
+		57.81754, 54.64578, 51.6241, 48.52903, 45.77973, 43.16302, 40.66998, 38.28514,
+		35.85076, 33.72253, 31.6593, 29.70163, 27.75588, 26.00589, 24.40699, 22.85243,
+		21.36159, 19.86847, 18.58402, 17.33423, 16.16289, 14.99657, 13.96672, 13.01505,
+		12.1119, 11.2464, 10.40646, 9.692799, 9.033356, 8.392105, 7.757175, 7.244726,
+		6.688352, 6.164142, 5.704995, 5.266929, 4.837553, 4.431206, 4.076215, 3.704869,
+		3.420255, 3.134089, 2.841858, 2.646573, 2.426696, 2.231887, 2.063467, 1.910739,
+		1.693676, 1.480057, 1.36338, 1.264239, 1.11944, 1.019061, 0.8359813, 0.7388805,
+		0.6218879, 0.4944512, 0.4916774, 0.4647724, 0.3728586, 0.3163272, 0.2735869, 0.3200322,
+		0.2767634, 0.29008, 0.2104815, 0.157422, 0.2036382, 0.1792264, 0.1883876, 0.1104106,
+		0.1160349, 0.07504696, 0.06913481, 0.1020219,
+	},
+	// log2m = 9
+	{
+		368.529, 354.1639, 340.1371, 325.9851, 312.6849, 299.2793, 286.7035, 274.0542,
+		262.2031, 250.2901, 239.1279, 228.3312, 217.4767, 207.4054, 197.2762, 187.8768,
+		178.4793, 169.7173, 160.9691, 152.8516, 145.0612, 137.3011, 130.16, 123.0708,
+		116.4666, 109.9044, 103.9159, 97.97173, 92.4647, 87.20873, 81.99949, 77.20372,
+		72.49207, 68.16721, 63.99557, 60.14141, 56.3639, 52.92863, 49.62172, 46.35181,
+		43.41789, 40.56202, 37.92305, 35.28962, 32.981, 30.72467, 28.69009, 26.64896,
+		24.77881, 23.11196, 21.42567, 19.86822, 18.3944, 17.11347, 15.81529, 14.61275,
+		13.5442, 12.519, 11.65395, 10.7855, 10.05028, 9.348563, 8.60242, 7.982135,
+		7.560246, 6.923646, 6.52178, 6.123659, 5.65848, 5.33658, 4.906999, 4.601673,
+		4.157209, 3.973266, 3.599341, 3.244886, 2.887042, 2.65066, 2.445623, 2.261771,
+		1.989435, 1.895497, 1.723964, 1.66635, 1.432379, 1.284619, 1.187389, 1.13525,
+		1.101278, 1.029863, 0.8822103, 1.053504, 1.044314, 1.023784, 0.8906581, 0.7146947,
+		0.6782295, 0.6701906, 0.6541859, 0.3905111,
+	},
+	// log2m = 10
+	{
+		737.8337, 709.0922, 680.5625, 652.7724, 625.7383, 599.4115, 573.8333, 548.979,
+		524.8453, 501.4448, 479.1518, 457.1618, 435.9147, 415.3748, 395.5087, 376.3421,
+		357.9151, 340.055, 322.9465, 306.7024, 290.7974, 275.5168, 260.8281, 246.7641,
+		233.2991, 220.4267, 208.0447, 196.3461, 185.2828, 174.5101, 164.2929, 154.5839,
+		145.37, 136.6043, 128.2828, 120.3369, 112.8378, 105.9321, 99.29164, 92.954,
+		86.8794, 81.20285, 75.82729, 70.73053, 65.94306, 61.42961, 57.17397, 53.23229,
+		49.61087, 46.12727, 42.8634, 39.89775, 36.97018, 34.33578, 31.69786, 29.52645,
+		27.33404, 25.24351, 23.50628, 21.82227, 20.0791, 18.3322, 17.1403, 15.76413,
+		14.44022, 13.27691, 12.21631, 11.13051, 10.17484, 9.396144, 8.506388, 7.779331,
+		7.176552, 6.616091, 6.155872, 5.690252, 5.360925, 4.81629, 4.608463, 4.268708,
+		3.90468, 3.567457, 3.525268, 3.271828, 2.861493, 2.469291, 2.127356, 2.044805,
+		1.823654, 1.452479, 1.189622, 1.003266, 1.062531, 0.9824674, 0.9666963, 0.9782219,
+		1.151126, 0.7776658, 1.143073, 1.355869,
+	},
+	// log2m = 11
+	{
+		1476.445, 1418.515, 1361.48, 1305.927, 1251.777, 1199.577, 1148.475, 1098.755,
+		1050.503, 1003.749, 958.8008, 914.8659, 872.3842, 831.3794, 792.0819, 753.9493,
+		716.9607, 681.3398, 646.9545, 614.2038, 582.3387, 551.8856, 522.6249, 495.0376,
+		468.2798, 442.5527, 417.9393, 394.5209, 372.339, 350.8883, 330.5537, 311.4187,
+		293.0236, 275.3267, 258.704, 242.6997, 227.8274, 213.7556, 200.3912, 187.3859,
+		175.316, 164.0328, 153.2962, 143.3601, 134.0513, 125.1762, 116.6607, 108.7983,
+		101.4038, 93.92757, 87.23636, 81.21101, 74.71835, 69.16393, 64.45247, 59.68725,
+		55.20354, 51.31783, 47.89339, 44.03457, 40.43167, 37.00437, 34.25792, 31.42448,
+		28.90274, 26.38248, 24.34916, 22.74189, 20.70268, 19.33913, 17.83136, 16.01329,
+		14.6376, 13.28625, 12.27191, 11.55249, 10.61305, 10.02044, 9.153325, 8.358503,
+		7.413728, 6.624717, 6.263371, 5.867291, 5.45789, 5.295792, 4.535077, 4.074652,
+		3.908004, 3.808296, 3.390471, 3.018466, 3.352543, 2.923248, 3.175861, 2.265055,
+		1.648698, 1.46038, 1.643951, 1.44066,
+	},
+	// log2m = 12
+	{
+		2953.667, 2837.267, 2723.309, 2612.712, 2504.603, 2399.934, 2297.618, 2198.711,
+		2102.219, 2008.742, 1918.563, 1830.677, 1746.134, 1664.11, 1585.108, 1508.535,
+		1434.957, 1363.469, 1294.903, 1229.166, 1165.628, 1104.945, 1046.62, 990.6356,
+		936.88, 885.6715, 836.2869, 789.1834, 744.2678, 701.7624, 660.9238, 622.3663,
+		585.2224, 549.5973, 516.8392, 484.102, 453.669, 425.5925, 397.6203, 372.1751,
+		347.9649, 324.8207, 303.5542, 283.1484, 264.3232, 246.5954, 229.7671, 214.1889,
+		199.2139, 184.9911, 172.2964, 159.8177, 148.0172, 136.6973, 127.0145, 118.632,
+		110.7448, 103.0639, 95.70037, 88.37756, 80.76374, 74.48768, 69.95083, 64.78256,
+		58.98392, 54.76017, 50.53632, 46.22292, 42.69313, 39.41159, 36.34183, 34.06858,
+		31.1134, 29.52441, 27.5515, 25.86025, 23.71226, 21.12206, 19.71331, 18.64758,
+		17.20078, 16.06165, 15.05478, 12.82957, 12.15508, 11.26124, 10.83149, 10.31458,
+		9.132128, 8.083664, 6.713592, 5.543427, 3.80082, 2.762828, 2.103703, 2.111647,
+		1.551275, 0.4705894, 0.7362432, 0.5239823,
+	},
+	// log2m = 13
+	{
+		5908.111, 5674.723, 5447.11, 5225.357, 5009.454, 4799.488, 4595.339, 4396.89,
+		4204.308, 4017.228, 3836.026, 3660.726, 3491.164, 3327.109, 3168.552, 3015.159,
+		2867.46, 2725.511, 2588.346, 2456.389, 2328.847, 2207.213, 2090.448, 1978.288,
+		1871.817, 1768.579, 1670.199, 1577.069, 1487.094, 1401.351, 1319.212, 1241.788,
+		1168.311, 1098.951, 1032.444, 968.5772, 907.0474, 850.6726, 797.0638, 745.6959,
+		696.3043, 650.8427, 607.7051, 567.1593, 528.2118, 492.1773, 457.5017, 426.5518,
+		396.378, 367.8233, 341.5999, 316.2373, 292.6218, 271.5281, 250.8047, 231.5705,
+		213.6387, 197.8327, 181.9567, 168.2838, 153.5707, 142.0692, 129.5225, 119.309,
+		111.0556, 100.7928, 92.196, 84.7649, 78.10712, 70.87231, 64.30718, 60.26714,
+		54.92098, 49.83495, 46.08971, 41.44883, 39.37435, 35.52866, 31.94555, 29.30098,
+		25.85465, 24.30033, 22.75331, 19.50936, 17.74465, 15.09246, 14.08096, 13.58031,
+		13.09414, 11.2305, 9.86901, 8.187337, 6.685325, 6.147687, 6.442477, 9.292693,
+		7.474461, 7.120791, 5.118643, 3.063678,
+	},
+	// log2m = 14
+	{
+		11817.0, 11350.38, 10895.22, 10451.84, 10019.82, 9599.009, 9190.479, 8793.288,
+		8408.091, 8034.143, 7672.276, 7321.923, 6981.877, 6653.343, 6336.1, 6029.779,
+		5734.189, 5449.532, 5174.938, 4910.401, 4657.149, 4414.205, 4181.039, 3957.278,
+		3741.677, 3537.196, 3340.672, 3152.704, 2973.046, 2802.513, 2639.221, 2484.433,
+		2335.488, 2194.268, 2059.141, 1931.836, 1812.518, 1700.713, 1593.505, 1492.902,
+		1395.001, 1301.095, 1215.397, 1135.154, 1057.964, 985.4359, 918.7515, 855.36,
+		796.4629, 738.5946, 684.1299, 635.015, 589.3908, 545.8598, 507.2828, 468.4169,
+		437.238, 408.7434, 376.1719, 346.8125, 320.2496, 291.8328, 266.0442, 243.899,
+		222.7737, 205.2747, 190.4987, 174.7357, 160.3662, 143.5564, 133.23, 118.9528,
+		109.3661, 99.18077, 89.37324, 82.13177, 77.65285, 68.9909, 61.59606, 53.09096,
+		46.86426, 42.343, 39.14322, 33.61117, 34.3053, 26.6296, 27.19739, 24.73795,
+		23.36373, 17.67933, 17.08252, 16.32752, 16.95419, 10.70872, 6.465193, 5.792765,
+		6.945854, 6.646253, 4.096949, -0.5861164,
+	},
+	// log2m = 15
+	{
+		23634.78, 22701.25, 21790.93, 20903.5, 20040.5, 19199.38, 18382.4, 17588.91,
+		16818.58, 16071.29, 15346.92, 14644.61, 13964.37, 13308.37, 12675.4, 12063.12,
+		11471.2, 10900.5, 10351.3, 9823.601, 9314.879, 8825.486, 8357.929, 7910.293,
+		7480.467, 7069.862, 6676.793, 6300.118, 5940.299, 5599.168, 5270.627, 4962.358,
+		4664.154, 4382.222, 4113.738, 3860.426, 3617.4, 3386.387, 3173.258, 2969.2,
+		2780.341, 2596.802, 2424.519, 2262.165, 2106.814, 1964.545, 1826.743, 1698.462,
+		1576.058, 1461.059, 1359.873, 1256.626, 1164.373, 1074.577, 990.2011, 910.4432,
+		836.016, 767.0608, 708.4909, 651.8308, 600.5051, 553.2422, 506.3218, 468.7286,
+		429.2413, 394.7626, 366.8317, 335.1801, 311.6231, 281.8674, 257.3682, 231.231,
+		212.0077, 194.1194, 180.7595, 157.5143, 138.3091, 126.8549, 118.0709, 102.1516,
+		90.65184, 82.36848, 76.26179, 73.72417, 58.2849, 50.02493, 41.90944, 37.82586,
+		29.40133, 28.90193, 23.88739, 29.29131, 18.12532, 19.78542, 10.03729, 1.014889,
+		-9.93929, -4.288158, -1.531209, -9.010571,
+	},
+	// log2m = 16
+	{
+		47270.34, 45403.9, 43582.66, 41808.14, 40081.1, 38400.76, 36767.56, 35180.92,
+		33640.9, 32147.57, 30699.57, 29297.3, 27936.18, 26622.67, 25356.38, 24131.61,
+		22949.54, 21812.83, 20715.56, 19660.49, 18649.54, 17676.72, 16741.98, 15843.25,
+		14983.4, 14160.71, 13375.49, 12623.69, 11913.52, 11230.45, 10581.85, 9964.675,
+		9367.792, 8806.681, 8271.186, 7768.403, 7285.879, 6827.008, 6398.062, 5988.632,
+		5596.863, 5242.314, 4904.614, 4584.593, 4285.839, 4005.823, 3738.673, 3480.793,
+		3237.363, 3016.908, 2810.599, 2608.725, 2427.348, 2258.961, 2096.322, 1946.264,
+		1800.216, 1667.298, 1540.507, 1426.541, 1328.179, 1226.051, 1134.998, 1045.281,
+		967.0293, 888.9408, 815.3964, 738.6916, 676.9554, 612.5033, 564.3215, 517.4277,
+		484.1412, 444.9189, 403.9323, 371.2578, 341.5337, 328.1585, 294.9538, 265.467,
+		234.3245, 220.0864, 205.8545, 191.0412, 177.0756, 170.6621, 158.904, 141.1992,
+		124.3224, 110.4537, 80.18942, 78.35947, 73.26109, 54.12762, 51.64489, 65.30137,
+		43.72446, 27.09589, 20.42654, 39.8768,
+	},
+	// log2m = 17
+	{
+		94541.46, 90807.85, 87166.74, 83620.12, 80167.07, 76806.14, 73537.92, 70362.9,
+		67281.85, 64292.38, 61396.16, 58588.88, 55873.11, 53245.38, 50710.88, 48259.84,
+		45898.43, 43621.64, 41429.19, 39316.54, 37284.03, 35337.04, 33466.61, 31672.04,
+		29962.36, 28319.35, 26753.04, 25249.13, 23807.3, 22435.96, 21134.02, 19899.35,
+		18716.05, 17596.68, 16522.51, 15502.93, 14536.87, 13624.4, 12768.07, 11946.42,
+		11176.92, 10439.81, 9756.166, 9107.371, 8491.191, 7932.647, 7395.9, 6880.744,
+		6408.81, 5965.444, 5538.998, 5137.43, 4756.263, 4414.507, 4086.561, 3780.046,
+		3496.464, 3236.946, 2982.064, 2761.428, 2549.758, 2353.912, 2161.171, 1979.568,
+		1823.175, 1652.862, 1513.286, 1368.753, 1243.25, 1129.65, 1015.987, 936.3193,
+		849.4081, 753.8126, 669.821, 613.8365, 543.0246, 474.4094, 425.0509, 376.179,
+		329.0287, 300.7525, 270.1709, 239.5716, 227.6236, 202.8531, 164.1816, 145.9433,
+		122.5703, 106.9415, 74.54894, 64.61421, 32.68986, 16.11613, 6.794094, -3.63315,
+		-18.94929, -43.85904, -40.99372, -26.6578,
+	},
+	// log2m = 18
+	{
+		189083.7, 181615.5, 174333.5, 167239.0, 160329.3, 153607.8, 147071.6, 140722.4,
+		134560.3, 128579.3, 122781.8, 117167.3, 111740.0, 106487.6, 101416.7, 96518.22,
+		91796.55, 87245.27, 82857.69, 78638.52, 74587.28, 70692.93, 66944.01, 63355.78,
+		59919.51, 56636.46, 53490.83, 50481.32, 47616.53, 44877.28, 42259.74, 39780.9,
+		37409.19, 35161.88, 33023.53, 30995.63, 29074.04, 27239.99, 25514.26, 23890.03,
+		22345.16, 20886.61, 19499.35, 18209.42, 16977.99, 15823.71, 14746.42, 13743.47,
+		12791.73, 11906.36, 11071.83, 10297.63, 9578.425, 8869.941, 8230.891, 7617.718,
+		7056.308, 6539.952, 6071.123, 5626.327, 5210.76, 4813.556, 4451.377, 4109.323,
+		3787.15, 3497.829, 3203.337, 2970.087, 2740.999, 2503.372, 2301.443, 2145.327,
+		1958.997, 1793.626, 1642.236, 1525.335, 1399.849, 1303.063, 1180.091, 1082.037,
+		1038.834, 946.4941, 875.676, 788.7601, 734.1333, 670.079, 599.6445, 538.5456,
+		476.5132, 442.6521, 411.6098, 388.429, 343.5951, 316.7173, 335.0598, 280.123,
+		273.4367, 228.7322, 185.8251, 170.4379,
+	},
+}
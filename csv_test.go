@@ -0,0 +1,38 @@
+package hll
+
+import (
+	"bufio"
+	"compress/gzip"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readCSV calls fn with the columns and line number of each line of the
+// gzipped CSV file at path after its header.
+func readCSV(t *testing.T, path string, fn func(parts []string, lineNo int)) {
+
+	reader, err := os.Open(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	decompressed, err := gzip.NewReader(reader)
+	require.NoError(t, err)
+	defer decompressed.Close()
+
+	scanner := bufio.NewScanner(decompressed)
+	scanner.Buffer(nil, 1<<20)
+	require.True(t, scanner.Scan()) // discard header.
+	require.NoError(t, scanner.Err())
+
+	lineNo := 2 // line 1 was discarded above.
+
+	for scanner.Scan() {
+		fn(strings.Split(scanner.Text(), ","), lineNo)
+		lineNo++
+	}
+
+	require.NoError(t, scanner.Err())
+}
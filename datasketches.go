@@ -0,0 +1,555 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Druid, Spark, and Hive among others store HLL sketches from Apache
+// DataSketches (https://datasketches.apache.org).  The layout of the serialized
+// sketches is described in org.apache.datasketches.hll.PreambleUtil.
+//
+// A DataSketches HLL sketch hashes each value with MurmurHash3 x64_128 (seed
+// 9001) into a coupon, which is a 26 bit slot and a 6 bit value.  The slot is
+// the low bits of the first half of the hash, and the value is one plus the
+// number of leading zeros of the second half.  While the sketch is small, the
+// coupons are kept in a list or hash set.  Afterwards, the sketch uses 2^lgK
+// registers indexed by the low lgK bits of the slot.  Each register is stored
+// in 4 (HLL_4), 6 (HLL_6), or 8 (HLL_8) bits, but they all hold the same values,
+// so the type only affects the size of the sketch.
+//
+// An Hll represents a coupon with a synthetic raw value whose low log2m bits
+// are the low bits of the slot, whose next set bit gives the register value as
+// the storage spec defines it, and whose remaining bits hold the rest of the
+// slot.  Registers are then the same in both representations, and an explicit
+// Hll keeps the same distinct coupons as a list or hash set.
+
+// DataSketchesType is the size of the registers of a DataSketches HLL sketch.
+type DataSketchesType byte
+
+const (
+	// DataSketchesHLL4 stores registers in 4 bits relative to the minimum
+	// register value along with a table of exceptions.  It's the smallest.
+	DataSketchesHLL4 DataSketchesType = iota
+	// DataSketchesHLL6 stores registers in 6 bits.
+	DataSketchesHLL6
+	// DataSketchesHLL8 stores registers in a byte each.  It's the default in
+	// DataSketches.
+	DataSketchesHLL8
+)
+
+const (
+	dataSketchesSerVer = 1
+	dataSketchesFamily = 7
+
+	dataSketchesMinLgK = 4
+	dataSketchesMaxLgK = 21
+
+	// the register values of every type fit in 6 bits.
+	dataSketchesRegwidth = 6
+
+	dataSketchesSeed     = 9001
+	dataSketchesSlotMask = 1<<26 - 1
+	dataSketchesMaxLz    = 62
+
+	// flags byte.
+	dataSketchesEmptyFlag      = 1 << 2
+	dataSketchesCompactFlag    = 1 << 3
+	dataSketchesOutOfOrderFlag = 1 << 4
+
+	// the mode is held in the low 2 bits of the mode byte.
+	dataSketchesList = 0
+	dataSketchesSet  = 1
+	dataSketchesHll  = 2
+
+	// byte offsets of the preamble.
+	dataSketchesPreIntsByte   = 0
+	dataSketchesSerVerByte    = 1
+	dataSketchesFamilyByte    = 2
+	dataSketchesLgKByte       = 3
+	dataSketchesLgArrByte     = 4
+	dataSketchesFlagsByte     = 5
+	dataSketchesCountByte     = 6 // list count or HLL curMin.
+	dataSketchesModeByte      = 7
+	dataSketchesListStart     = 8
+	dataSketchesSetCount      = 8
+	dataSketchesSetStart      = 12
+	dataSketchesAuxCount      = 36
+	dataSketchesHllStart      = 40
+	dataSketchesListPreInts   = 2
+	dataSketchesSetPreInts    = 3
+	dataSketchesHllPreInts    = 10
+	dataSketchesListLgArr     = 3
+	dataSketchesSetMinLgArr   = 5
+	dataSketchesMaxListLength = 1 << dataSketchesListLgArr
+
+	// HLL_4 registers equal to the minimum plus this value or more are stored
+	// in the exception table.
+	dataSketchesAuxToken = 15
+)
+
+// dataSketchesLgAuxArrInts is the initial size of the HLL_4 exception table by
+// lgK.
+var dataSketchesLgAuxArrInts = [...]int{0, 2, 2, 2, 2, 2, 2, 3, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 11, 12, 13}
+
+// errInvalidCoupon is returned by FromDataSketches for a coupon or exception
+// with a value of zero, an HLL_4 exception whose value would fit in a nibble,
+// or an HLL_4 nibble that marks an exception that doesn't exist.
+var errInvalidCoupon = errors.New("invalid DataSketches coupon")
+
+// DataSketchesHasher returns a Hasher that gives values the same registers as
+// a DataSketches HLL sketch with the provided lgConfigK gives them when they're
+// added with update(byte[]), update(String), or update(long).  The Hlls using
+// it must have a log2m equal to lgConfigK.  Note that DataSketches ignores
+// empty strings and byte arrays, whereas AddBytes and AddString do not.
+func DataSketchesHasher(lgConfigK int) Hasher {
+	return dataSketchesHasher{log2m: lgConfigK}
+}
+
+// DataSketchesSettings returns the settings of the Hlls returned by
+// FromDataSketches for a sketch with the provided lgConfigK.  Values added to
+// these Hlls with AddBytes, AddString, or AddInt64 are hashed with
+// DataSketchesHasher, so they can be unioned with imported sketches.  The
+// explicit threshold is large enough to hold the coupons of any sketch in list
+// or hash set mode, except that it's limited to 131,072 when lgConfigK is 21.
+func DataSketchesSettings(lgConfigK int) Settings {
+
+	// a list holds up to 7 coupons, and a hash set holds up to 3/4 of 2^(lgK-3)
+	// coupons.
+	explicitThreshold := dataSketchesMaxListLength
+	if lgConfigK > dataSketchesListLgArr+3 {
+		explicitThreshold = 1 << uint(lgConfigK-3)
+	}
+	if explicitThreshold > maximumExplicitThreshold {
+		explicitThreshold = maximumExplicitThreshold
+	}
+
+	return Settings{
+		Log2m:             lgConfigK,
+		Regwidth:          dataSketchesRegwidth,
+		ExplicitThreshold: explicitThreshold,
+		SparseEnabled:     true,
+		Hasher:            DataSketchesHasher(lgConfigK),
+	}
+}
+
+type dataSketchesHasher struct {
+	log2m int
+}
+
+func (d dataSketchesHasher) Sum64(data []byte) uint64 {
+
+	h1, h2 := murmur3Sum128(data, dataSketchesSeed)
+
+	lz := bits.LeadingZeros64(h2)
+	if lz > dataSketchesMaxLz {
+		lz = dataSketchesMaxLz
+	}
+
+	return dataSketchesRaw(d.log2m, uint32(h1)&dataSketchesSlotMask, lz+1)
+}
+
+// dataSketchesRaw returns the raw value that represents the coupon with the
+// slot and value in an Hll with the provided log2m.
+func dataSketchesRaw(log2m int, slot uint32, value int) uint64 {
+
+	// the position of the bit that gives the register value.  the register
+	// value is limited for large log2m, though reaching that limit is about
+	// as likely as a hash of zero.
+	marker := uint(log2m + value - 1)
+	if marker > 63 {
+		marker = 63
+	}
+
+	return uint64(slot)&(1<<uint(log2m)-1) | 1<<marker | uint64(slot>>uint(log2m))<<(marker+1)
+}
+
+// dataSketchesCoupon is the inverse of dataSketchesRaw.  It returns false for
+// raw values that don't set a register.
+func dataSketchesCoupon(log2m int, raw uint64) (uint32, bool) {
+
+	substream := raw >> uint(log2m)
+	if substream == 0 {
+		return 0, false
+	}
+
+	tz := bits.TrailingZeros64(substream)
+	slot := raw&(1<<uint(log2m)-1) | (substream>>uint(tz+1))<<uint(log2m)
+
+	return uint32(tz+1)<<26 | uint32(slot)&dataSketchesSlotMask, true
+}
+
+// FromDataSketches converts a serialized DataSketches HLL sketch of any type to
+// an Hll with DataSketchesSettings for its lgConfigK.  Both the compact and
+// updatable forms are supported.  Sketches in list or hash set mode become
+// explicit Hlls with one value per coupon (or sparse or dense Hlls if there are
+// more coupons than the explicit threshold), and sketches in HLL mode become
+// sparse or dense Hlls with the same registers.  The HIP estimate is ignored.
+func FromDataSketches(data []byte) (Hll, error) {
+
+	if len(data) < dataSketchesListStart {
+		return Hll{}, ErrInsufficientBytes
+	}
+	if data[dataSketchesSerVerByte] != dataSketchesSerVer {
+		return Hll{}, fmt.Errorf("unsupported DataSketches serialization version: %d", data[dataSketchesSerVerByte])
+	}
+	if data[dataSketchesFamilyByte] != dataSketchesFamily {
+		return Hll{}, fmt.Errorf("not a DataSketches HLL sketch: family %d", data[dataSketchesFamilyByte])
+	}
+
+	lgK := int(data[dataSketchesLgKByte])
+	if lgK < dataSketchesMinLgK || lgK > dataSketchesMaxLgK {
+		return Hll{}, fmt.Errorf("invalid DataSketches lgConfigK: %d", lgK)
+	}
+
+	mode := data[dataSketchesModeByte] & 0x3
+	hllType := DataSketchesType(data[dataSketchesModeByte] >> 2 & 0x3)
+	if hllType > DataSketchesHLL8 {
+		return Hll{}, fmt.Errorf("invalid DataSketches HLL type: %d", hllType)
+	}
+
+	settings, err := DataSketchesSettings(lgK).toInternal()
+	if err != nil {
+		return Hll{}, err
+	}
+
+	h := Hll{settings: settings}
+
+	if data[dataSketchesFlagsByte]&dataSketchesEmptyFlag != 0 {
+		return h, nil
+	}
+
+	compact := data[dataSketchesFlagsByte]&dataSketchesCompactFlag != 0
+	lgArr := int(data[dataSketchesLgArrByte])
+
+	var coupons []byte
+	switch mode {
+	case dataSketchesList:
+		coupons, err = dataSketchesInts(data[dataSketchesListStart:], int(data[dataSketchesCountByte]), compact, lgArr)
+	case dataSketchesSet:
+		if len(data) < dataSketchesSetStart {
+			return Hll{}, ErrInsufficientBytes
+		}
+		count := int(int32(binary.LittleEndian.Uint32(data[dataSketchesSetCount:])))
+		coupons, err = dataSketchesInts(data[dataSketchesSetStart:], count, compact, lgArr)
+	case dataSketchesHll:
+		err = h.readDataSketchesRegisters(data, hllType, compact)
+	default:
+		return Hll{}, fmt.Errorf("invalid DataSketches mode: %d", mode)
+	}
+
+	if err != nil {
+		return Hll{}, err
+	}
+
+	for i := 0; i < len(coupons); i += 4 {
+		coupon := binary.LittleEndian.Uint32(coupons[i:])
+		if coupon == 0 {
+			// empty slots of an updatable list or hash set.
+			continue
+		}
+		if coupon>>26 == 0 {
+			return Hll{}, errInvalidCoupon
+		}
+		h.AddRaw(dataSketchesRaw(lgK, coupon&dataSketchesSlotMask, int(coupon>>26)))
+	}
+
+	if h.storage != nil && h.storage.overCapacity(settings) {
+		h.upgrade()
+	}
+
+	return h, nil
+}
+
+// dataSketchesInts returns the bytes of the coupons or exceptions at the start
+// of the data.  The compact form holds count of them, and the updatable form
+// holds a table of 2^lgArr of them, some of which are empty.
+func dataSketchesInts(data []byte, count int, compact bool, lgArr int) ([]byte, error) {
+
+	size := 4 * count
+	if !compact {
+		if lgArr > dataSketchesMaxLgK {
+			return nil, fmt.Errorf("invalid DataSketches lgArr: %d", lgArr)
+		}
+		size = 4 << uint(lgArr)
+	}
+
+	if count < 0 || len(data) < size {
+		return nil, ErrInsufficientBytes
+	}
+
+	return data[:size], nil
+}
+
+// readDataSketchesRegisters sets the registers from a sketch in HLL mode.
+func (h *Hll) readDataSketchesRegisters(data []byte, hllType DataSketchesType, compact bool) error {
+
+	if len(data) < dataSketchesHllStart {
+		return ErrInsufficientBytes
+	}
+
+	m := 1 << uint(h.settings.log2m)
+	size := dataSketchesArraySize(hllType, m)
+	if len(data) < dataSketchesHllStart+size {
+		return ErrInsufficientBytes
+	}
+	registers := data[dataSketchesHllStart : dataSketchesHllStart+size]
+
+	var value func(regnum int) (byte, error)
+	switch hllType {
+	case DataSketchesHLL4:
+		auxCount := int(int32(binary.LittleEndian.Uint32(data[dataSketchesAuxCount:])))
+		auxBytes, err := dataSketchesInts(data[dataSketchesHllStart+size:], auxCount, compact, int(data[dataSketchesLgArrByte]))
+		if err != nil {
+			return err
+		}
+
+		// registers are only held in the exceptions when they're at least
+		// curMin+15, which is what the nibble would otherwise hold.
+		curMin := data[dataSketchesCountByte]
+		aux := make(map[int]byte, auxCount)
+		for i := 0; i < len(auxBytes); i += 4 {
+			exception := binary.LittleEndian.Uint32(auxBytes[i:])
+			if exception == 0 {
+				continue
+			}
+			if int(exception>>26) < int(curMin)+dataSketchesAuxToken {
+				return errInvalidCoupon
+			}
+			aux[int(exception)&(m-1)] = byte(exception >> 26)
+		}
+
+		value = func(regnum int) (byte, error) {
+			nibble := registers[regnum>>1] >> (4 * uint(regnum&1)) & 0xf
+			if nibble != dataSketchesAuxToken {
+				return curMin + nibble, nil
+			}
+			if v, ok := aux[regnum]; ok {
+				return v, nil
+			}
+			return 0, errInvalidCoupon
+		}
+	case DataSketchesHLL6:
+		value = func(regnum int) (byte, error) { return getLSB6(registers, regnum), nil }
+	case DataSketchesHLL8:
+		value = func(regnum int) (byte, error) { return registers[regnum], nil }
+	}
+
+	for regnum := 0; regnum < m; regnum++ {
+		v, err := value(regnum)
+		if err != nil {
+			return err
+		}
+		if uint64(v) > h.settings.regwidthMask {
+			return fmt.Errorf("invalid DataSketches register value: %d", v)
+		}
+		h.setRegister(regnum, v)
+	}
+
+	return nil
+}
+
+// dataSketchesArraySize returns the size in bytes of the registers of a sketch
+// in HLL mode.  HLL_6 has an additional byte so that every register can be
+// read as a 16 bit value.
+func dataSketchesArraySize(hllType DataSketchesType, m int) int {
+	switch hllType {
+	case DataSketchesHLL4:
+		return m / 2
+	case DataSketchesHLL6:
+		return m*3/4 + 1
+	default:
+		return m
+	}
+}
+
+// ToDataSketches converts the Hll to a compact DataSketches HLL sketch of the
+// provided type, which DataSketches can read with HllSketch.heapify and wrap.
+// The lgConfigK is the Hll's log2m, which must be between 4 and 21.
+//
+// Explicit Hlls with few enough values become sketches in list or hash set
+// mode, as they would in DataSketches.  Otherwise, the sketch is in HLL mode
+// with the Hll's registers, clamped to 6 bits.  Since the Hll doesn't have a
+// HIP estimate, the sketch is marked out of order so that DataSketches uses
+// its composite estimator, as it does for the result of a union.
+func (h *Hll) ToDataSketches(hllType DataSketchesType) ([]byte, error) {
+
+	h.initOrPanic()
+
+	if hllType > DataSketchesHLL8 {
+		return nil, fmt.Errorf("invalid DataSketches HLL type: %d", hllType)
+	}
+
+	lgK := h.settings.log2m
+	if lgK < dataSketchesMinLgK || lgK > dataSketchesMaxLgK {
+		return nil, fmt.Errorf("cannot convert Log2m %d to DataSketches.  Must be between %d and %d", lgK, dataSketchesMinLgK, dataSketchesMaxLgK)
+	}
+
+	header := []byte{
+		dataSketchesPreIntsByte: dataSketchesListPreInts,
+		dataSketchesSerVerByte:  dataSketchesSerVer,
+		dataSketchesFamilyByte:  dataSketchesFamily,
+		dataSketchesLgKByte:     byte(lgK),
+		dataSketchesLgArrByte:   dataSketchesListLgArr,
+		dataSketchesFlagsByte:   dataSketchesCompactFlag,
+		dataSketchesCountByte:   0,
+		dataSketchesModeByte:    byte(hllType) << 2,
+	}
+
+	if explicit, ok := h.storage.(explicitStorage); ok {
+		coupons := make([]uint32, 0, len(explicit))
+		seen := make(map[uint32]struct{}, len(explicit))
+		for raw := range explicit {
+			coupon, ok := dataSketchesCoupon(lgK, raw)
+			if _, dup := seen[coupon]; !ok || dup {
+				continue
+			}
+			seen[coupon] = struct{}{}
+			coupons = append(coupons, coupon)
+		}
+		sort.Slice(coupons, func(i, j int) bool { return coupons[i] < coupons[j] })
+
+		if len(coupons) < dataSketchesMaxListLength {
+			return dataSketchesCoupons(header, dataSketchesList, coupons), nil
+		}
+
+		// like DataSketches, only lgK of 8 or more has a hash set, and it's
+		// promoted to HLL mode rather than growing to 2^(lgK-3) coupons.
+		if lgArr := dataSketchesLgArr(dataSketchesSet, len(coupons), lgK); lgK >= 8 && lgArr <= lgK-3 {
+			return dataSketchesCoupons(header, dataSketchesSet, coupons), nil
+		}
+	}
+
+	registers, empty := h.convertedRegisters(lgK, dataSketchesRegwidth)
+	if empty {
+		header[dataSketchesFlagsByte] |= dataSketchesEmptyFlag
+		return header, nil
+	}
+
+	return h.dataSketchesHll(header, hllType, registers), nil
+}
+
+// dataSketchesCoupons returns a compact sketch in list or hash set mode.  An
+// empty list is flagged as such.
+func dataSketchesCoupons(header []byte, mode byte, coupons []uint32) []byte {
+
+	data := header
+	data[dataSketchesModeByte] |= mode
+
+	switch {
+	case len(coupons) == 0:
+		data[dataSketchesFlagsByte] |= dataSketchesEmptyFlag
+	case mode == dataSketchesList:
+		data[dataSketchesCountByte] = byte(len(coupons))
+	default:
+		data[dataSketchesPreIntsByte] = dataSketchesSetPreInts
+		data[dataSketchesLgArrByte] = byte(dataSketchesLgArr(dataSketchesSet, len(coupons), int(data[dataSketchesLgKByte])))
+		data = appendUint32LE(data, uint32(len(coupons)))
+	}
+
+	for _, coupon := range coupons {
+		data = appendUint32LE(data, coupon)
+	}
+
+	return data
+}
+
+// dataSketchesHll returns a compact sketch in HLL mode with the registers.
+func (h *Hll) dataSketchesHll(header []byte, hllType DataSketchesType, registers []byte) []byte {
+
+	lgK := int(header[dataSketchesLgKByte])
+
+	// kxq0 and kxq1 are the sums of 2^-value for the registers less than and at
+	// least 32, which DataSketches keeps separately to retain precision.
+	var kxq0, kxq1 float64
+	for _, value := range registers {
+		if value < 32 {
+			kxq0 += math.Ldexp(1, -int(value))
+		} else {
+			kxq1 += math.Ldexp(1, -int(value))
+		}
+	}
+
+	// curMin is the minimum register value for HLL_4 and zero otherwise.
+	var curMin byte
+	if hllType == DataSketchesHLL4 {
+		curMin = registers[0]
+		for _, value := range registers {
+			if value < curMin {
+				curMin = value
+			}
+		}
+	}
+
+	numAtCurMin := 0
+	for _, value := range registers {
+		if value == curMin {
+			numAtCurMin++
+		}
+	}
+
+	array := make([]byte, dataSketchesArraySize(hllType, len(registers)))
+	var aux []uint32
+	lgArr := 0
+
+	switch hllType {
+	case DataSketchesHLL4:
+		for regnum, value := range registers {
+			nibble := value - curMin
+			if nibble >= dataSketchesAuxToken {
+				nibble = dataSketchesAuxToken
+				aux = append(aux, uint32(value)<<26|uint32(regnum))
+			}
+			array[regnum>>1] |= nibble << (4 * uint(regnum&1))
+		}
+		lgArr = dataSketchesLgAuxArrInts[lgK]
+		if len(aux) > 0 {
+			lgArr = dataSketchesLgArr(dataSketchesHll, len(aux), lgK)
+		}
+	case DataSketchesHLL6:
+		for regnum, value := range registers {
+			setLSB6(array, regnum, value)
+		}
+	case DataSketchesHLL8:
+		copy(array, registers)
+	}
+
+	data := header
+	data[dataSketchesPreIntsByte] = dataSketchesHllPreInts
+	data[dataSketchesLgArrByte] = byte(lgArr)
+	data[dataSketchesFlagsByte] |= dataSketchesOutOfOrderFlag
+	data[dataSketchesCountByte] = curMin
+	data[dataSketchesModeByte] |= dataSketchesHll
+
+	data = appendUint64LE(data, math.Float64bits(float64(h.Cardinality())))
+	data = appendUint64LE(data, math.Float64bits(kxq0))
+	data = appendUint64LE(data, math.Float64bits(kxq1))
+	data = appendUint32LE(data, uint32(numAtCurMin))
+	data = appendUint32LE(data, uint32(len(aux)))
+	data = append(data, array...)
+	for _, exception := range aux {
+		data = appendUint32LE(data, exception)
+	}
+
+	return data
+}
+
+// dataSketchesLgArr returns the size of the hash set or HLL_4 exception table
+// that DataSketches would use for count entries, which keeps it no more than
+// 3/4 full.
+func dataSketchesLgArr(mode byte, count int, lgK int) int {
+
+	lgArr := bits.Len(uint(count - 1))
+	if 4*count > 3<<uint(lgArr) {
+		lgArr++
+	}
+
+	if mode == dataSketchesSet {
+		return maxInt(dataSketchesSetMinLgArr, lgArr)
+	}
+	return maxInt(dataSketchesLgAuxArrInts[lgK], lgArr)
+}
@@ -0,0 +1,238 @@
+package hll
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:generate go run gen_datasketches.go
+
+// Test_DataSketchesFixtures runs through the sketches in
+// testdata/datasketches/sketches.csv.gz, which are generated by
+// gen_datasketches.go, a port of the update path and serialization of
+// HllSketch from DataSketches 3.x.
+func Test_DataSketchesFixtures(t *testing.T) {
+	checkDataSketchesFixtures(t, "testdata/datasketches/sketches.csv.gz")
+}
+
+// Test_DataSketchesGolden runs through the sketches in
+// testdata/datasketches/golden.csv.gz, which are serialized by DataSketches
+// itself.  testdata/datasketches/Golden.java writes them and records the
+// version of the library that it ran with in golden.txt.
+func Test_DataSketchesGolden(t *testing.T) {
+
+	const path = "testdata/datasketches/golden.csv.gz"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("%s has not been generated by testdata/datasketches/Golden.java", path)
+	}
+
+	checkDataSketchesFixtures(t, path)
+}
+
+// checkDataSketchesFixtures runs through the sketches in a gzipped CSV file.
+// Each line contains the lgConfigK, type, and form of a sketch along with a
+// count n, and the sketch holds the strings "0" through "n-1" added with
+// update(String).
+func checkDataSketchesFixtures(t *testing.T, path string) {
+
+	types := map[string]DataSketchesType{"HLL_4": DataSketchesHLL4, "HLL_6": DataSketchesHLL6, "HLL_8": DataSketchesHLL8}
+
+	readCSV(t, path, func(parts []string, lineNo int) {
+
+		require.Equal(t, 5, len(parts), "required 5 columns at line %d", lineNo)
+
+		lgK, err := strconv.Atoi(parts[0])
+		require.NoError(t, err, "invalid lg_k at line %d", lineNo)
+		hllType, ok := types[parts[1]]
+		require.True(t, ok, "invalid type at line %d", lineNo)
+		compact, err := strconv.ParseBool(parts[2])
+		require.NoError(t, err, "invalid compact at line %d", lineNo)
+		count, err := strconv.Atoi(parts[3])
+		require.NoError(t, err, "invalid count at line %d", lineNo)
+		require.True(t, strings.HasPrefix(parts[4], `\x`), "missing \\x at line %d", lineNo)
+		sketch, err := hex.DecodeString(parts[4][2:])
+		require.NoError(t, err, "invalid hex at line %d", lineNo)
+
+		expected := newHll(t, DataSketchesSettings(lgK))
+		for i := 0; i < count; i++ {
+			expected.AddString(strconv.Itoa(i))
+		}
+
+		decoded, err := FromDataSketches(sketch)
+		require.NoError(t, err, "failed to convert line %d", lineNo)
+
+		mode := sketch[dataSketchesModeByte] & 0x3
+		if mode == dataSketchesHll {
+			expectedRegisters, _ := expected.convertedRegisters(lgK, dataSketchesRegwidth)
+			decodedRegisters, _ := decoded.convertedRegisters(lgK, dataSketchesRegwidth)
+			require.Equal(t, expectedRegisters, decodedRegisters, "incorrect registers at line %d", lineNo)
+		} else {
+			// the coupons of list and hash set mode are kept exactly.
+			require.Equal(t, expected.ToBytes(), decoded.ToBytes(), "incorrect Hll at line %d", lineNo)
+		}
+
+		exported, err := decoded.ToDataSketches(hllType)
+		require.NoError(t, err)
+
+		reimported, err := FromDataSketches(exported)
+		require.NoError(t, err)
+		require.Equal(t, decoded.ToBytes(), reimported.ToBytes(), "incorrect round trip at line %d", lineNo)
+		require.Equal(t, sketch[dataSketchesModeByte], exported[dataSketchesModeByte], "incorrect mode at line %d", lineNo)
+
+		// other than the HIP estimate and flags, a sketch in HLL mode has a
+		// single compact form.  updatable HLL_4 sketches differ in the size of
+		// the exception table.
+		if mode == dataSketchesHll && (compact || hllType != DataSketchesHLL4) {
+			masked := append([]byte(nil), sketch...)
+			for _, b := range [][]byte{masked, exported} {
+				b[dataSketchesFlagsByte] = 0
+				copy(b[8:16], make([]byte, 8))
+			}
+			require.Equal(t, masked, exported, "incorrect sketch at line %d", lineNo)
+		}
+	})
+}
+
+func Test_DataSketchesCoupon(t *testing.T) {
+
+	for _, log2m := range []int{4, 11, 21} {
+		settings, err := DataSketchesSettings(log2m).toInternal()
+		require.NoError(t, err)
+
+		for _, slot := range []uint32{0, 1, 0x2a5, 0x1234567, dataSketchesSlotMask} {
+			for _, value := range []int{1, 2, 17, 32} {
+				raw := dataSketchesRaw(log2m, slot, value)
+
+				// the raw value sets the register that the coupon does.
+				regnum, register := settings.register(raw)
+				assert.Equal(t, int(slot)&(1<<uint(log2m)-1), regnum)
+				assert.Equal(t, byte(value), register)
+
+				coupon, ok := dataSketchesCoupon(log2m, raw)
+				require.True(t, ok)
+				assert.Equal(t, uint32(value)<<26|slot, coupon, fmt.Sprintf("log2m %d slot %x value %d", log2m, slot, value))
+			}
+		}
+	}
+
+	_, ok := dataSketchesCoupon(11, 0x7ff)
+	assert.False(t, ok)
+}
+
+func Test_ToDataSketches(t *testing.T) {
+
+	// Hlls with other settings and hashers are converted by their registers.
+	for _, settings := range []Settings{
+		{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+		{Log2m: 4, Regwidth: 8, ExplicitThreshold: 0, SparseEnabled: false},
+		{Log2m: 14, Regwidth: 6, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+	} {
+		for _, size := range []int{0, 1, 7, 8, 100, 10000} {
+			h := newHll(t, settings)
+			for i := 0; i < size; i++ {
+				h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+			}
+			expected, _ := h.convertedRegisters(settings.Log2m, dataSketchesRegwidth)
+
+			for _, hllType := range []DataSketchesType{DataSketchesHLL4, DataSketchesHLL6, DataSketchesHLL8} {
+				t.Run(fmt.Sprintf("%+v/%d/%d", settings, size, hllType), func(t *testing.T) {
+					sketch, err := h.ToDataSketches(hllType)
+					require.NoError(t, err)
+
+					decoded, err := FromDataSketches(sketch)
+					require.NoError(t, err)
+					actual, _ := decoded.convertedRegisters(settings.Log2m, dataSketchesRegwidth)
+					assert.Equal(t, expected, actual)
+
+					if size == 0 {
+						assert.Len(t, sketch, dataSketchesListStart)
+						assertEmpty(t, decoded)
+					}
+				})
+			}
+		}
+	}
+
+	// the exception table of HLL_4 holds registers 15 or more above the
+	// minimum.
+	h := newHll(t, Settings{Log2m: 4, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	for regnum := 0; regnum < 16; regnum++ {
+		h.setRegister(regnum, byte(2+regnum*4))
+	}
+	sketch, err := h.ToDataSketches(DataSketchesHLL4)
+	require.NoError(t, err)
+	assert.Equal(t, byte(2), sketch[dataSketchesCountByte])
+	assert.Equal(t, []byte{12, 0, 0, 0}, sketch[dataSketchesAuxCount:dataSketchesAuxCount+4])
+	decoded, err := FromDataSketches(sketch)
+	require.NoError(t, err)
+	assert.Equal(t, registerValues(h), registerValues(decoded))
+
+	large := newHll(t, Settings{Log2m: 22, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	_, err = large.ToDataSketches(DataSketchesHLL8)
+	assert.Error(t, err)
+
+	_, err = h.ToDataSketches(DataSketchesType(3))
+	assert.Error(t, err)
+}
+
+func Test_FromDataSketches_Errors(t *testing.T) {
+
+	h := newHll(t, DataSketchesSettings(4))
+	h.AddString("a")
+	list, err := h.ToDataSketches(DataSketchesHLL8)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		h.AddString(strconv.Itoa(i))
+	}
+	hll, err := h.ToDataSketches(DataSketchesHLL8)
+	require.NoError(t, err)
+
+	// an HLL_4 sketch whose last register is held in a single exception at the
+	// end.
+	h.AddRaw(dataSketchesRaw(4, 15, 40))
+	hll4, err := h.ToDataSketches(DataSketchesHLL4)
+	require.NoError(t, err)
+	curMin := hll4[dataSketchesCountByte]
+
+	modify := func(data []byte, offset int, value byte) []byte {
+		modified := append([]byte(nil), data...)
+		modified[offset] = value
+		return modified
+	}
+
+	tests := []struct {
+		label string
+		bytes []byte
+	}{
+		{"short", list[:7]},
+		{"version", modify(list, dataSketchesSerVerByte, 2)},
+		{"family", modify(list, dataSketchesFamilyByte, 15)},
+		{"small lgK", modify(list, dataSketchesLgKByte, 3)},
+		{"large lgK", modify(list, dataSketchesLgKByte, 22)},
+		{"type", modify(list, dataSketchesModeByte, 3<<2)},
+		{"mode", modify(list, dataSketchesModeByte, 3)},
+		{"list truncated", list[:len(list)-1]},
+		{"list count", modify(list, dataSketchesCountByte, 2)},
+		{"coupon value", modify(list, len(list)-1, 0)},
+		{"updatable lgArr", modify(modify(list, dataSketchesFlagsByte, 0), dataSketchesLgArrByte, 30)},
+		{"hll truncated", hll[:len(hll)-1]},
+		{"register value", modify(hll, dataSketchesHllStart, 64)},
+		{"missing exception", modify(hll4, dataSketchesAuxCount, 0)},
+		{"small exception", modify(hll4, len(hll4)-1, (curMin+14)<<2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			_, err := FromDataSketches(tt.bytes)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -16,8 +16,13 @@ type denseStorage []uint64
 // newDenseStorage allocates a new instance with sufficient space to store all
 // of the register values.
 func newDenseStorage(settings *settings) denseStorage {
-	bytes := divideBy8RoundUp((1 << uint(settings.log2m)) * settings.regwidth)
-	return make(denseStorage, divideBy8RoundUp(bytes))
+	return make(denseStorage, divideBy8RoundUp(denseSizeInBytes(settings)))
+}
+
+// denseSizeInBytes returns the number of bytes required to serialize every
+// register value.
+func denseSizeInBytes(settings *settings) int {
+	return divideBy8RoundUp((1 << uint(settings.log2m)) * settings.regwidth)
 }
 
 // overCapacity always returns false for dense storage because there is no
@@ -31,7 +36,7 @@ func (s denseStorage) overCapacity(settings *settings) bool {
 func (s denseStorage) sizeInBytes(settings *settings) int {
 	// NOTE : this does not calculate based on the array size b/c it's possible
 	//        that not every byte in the final word is used.
-	return divideBy8RoundUp((1 << uint(settings.log2m)) * settings.regwidth)
+	return denseSizeInBytes(settings)
 }
 
 // writeBytes writes out each register values in order.
@@ -55,20 +60,59 @@ func (s denseStorage) writeBytes(settings *settings, bytes []byte) {
 	}
 }
 
+// writeTo writes the same register values as writeBytes to the chunkWriter.
+func (s denseStorage) writeTo(settings *settings, w *chunkWriter) {
+
+	n := s.sizeInBytes(settings)
+	nWords := n / 8
+	for i := 0; i < nWords; {
+		count := nWords - i
+		if count > streamChunkSize/8 {
+			count = streamChunkSize / 8
+		}
+
+		bytes := w.next(count * 8)
+		for j := 0; j < count; j++ {
+			binary.BigEndian.PutUint64(bytes[j*8:], s[i+j])
+		}
+
+		i += count
+	}
+
+	remainder := n % 8
+	if remainder > 0 {
+		bytes := w.next(remainder)
+		lastWord := s[nWords]
+		for i := 0; i < remainder; i++ {
+			bytes[i] = byte(lastWord >> uint(64-(8*(i+1))))
+		}
+	}
+}
+
 // fromBytes deserializes the binary register values into this storage instance.
 func (s denseStorage) fromBytes(settings *settings, bytes []byte) error {
 
 	// ensure that every register is accounted for in the input byte slice.
-	if len(bytes) != divideBy8RoundUp((1<<uint(settings.log2m))*settings.regwidth) {
+	if len(bytes) != denseSizeInBytes(settings) {
 		return ErrInsufficientBytes
 	}
 
+	s.readWords(0, bytes)
+
+	return nil
+}
+
+// readWords deserializes a portion of the binary register values into the
+// storage starting at the provided word index.  Only the final portion may have
+// a length that is not a multiple of 8.
+func (s denseStorage) readWords(word int, bytes []byte) {
+
 	n := len(bytes)
 	nWords := n / 8
 
 	for i := 0; i < nWords; i++ {
 		offset := i * 8
-		s[i] = binary.BigEndian.Uint64(bytes[offset : offset+8])
+		s[word+i] = binary.BigEndian.Uint64(bytes[offset : offset+8])
 	}
 
 	// deal with any remaining bytes.  the binary reading function above doesn't
@@ -82,10 +126,8 @@ func (s denseStorage) fromBytes(settings *settings, bytes []byte) error {
 			lastValue |= uint64(bytes[n-(remainder-i)]) << shiftAmount
 		}
 
-		s[len(s)-1] = lastValue
+		s[word+nWords] = lastValue
 	}
-
-	return nil
 }
 
 func (s denseStorage) copy() storage {
@@ -101,7 +143,7 @@ func (s denseStorage) indicator(settings *settings) (float64, int) {
 	idx := 0
 	pos := 0
 	curr := s[idx]
-	mask := uint64(settings.mBitsMask << (64 - uint(settings.regwidth)))
+	mask := uint64(settings.regwidthMask << (64 - uint(settings.regwidth)))
 
 	sum := float64(0)
 	numberOfZeros := 0
@@ -140,7 +182,7 @@ func (s denseStorage) indicator(settings *settings) (float64, int) {
 
 			// prepare pos and mask for the next loop
 			pos = nLowerBits
-			mask = (settings.mBitsMask << uint(64-settings.regwidth)) >> uint(pos)
+			mask = (settings.regwidthMask << uint(64-settings.regwidth)) >> uint(pos)
 		}
 
 		// compute the "indicator function" -- indicator(2^(-M[j])) where M[j]
@@ -154,6 +196,18 @@ func (s denseStorage) indicator(settings *settings) (float64, int) {
 	return sum, numberOfZeros
 }
 
+func (s denseStorage) histogram(settings *settings) []int {
+
+	counts := make([]int, 1<<uint(settings.regwidth))
+
+	numReg := 1 << uint(settings.log2m)
+	for i := 0; i < numReg; i++ {
+		counts[s.get(i, settings.regwidth)]++
+	}
+
+	return clampHistogram(settings, counts)
+}
+
 func (s denseStorage) setIfGreater(settings *settings, regnum int, value byte) {
 
 	idx, pos := s.calcPosition(int(regnum), int(settings.regwidth))
@@ -202,8 +256,13 @@ func (s denseStorage) setIfGreater(settings *settings, regnum int, value byte) {
 // union is a special operation on denseStorage that will union other into the
 // receiver as a linear pass through the two backing slices.
 func (s denseStorage) union(settings *settings, other denseStorage) {
+	s.unionRegisters(settings, other, 1<<uint(settings.log2m))
+}
 
-	numReg := 1 << uint(settings.log2m)
+// unionRegisters unions the first numReg registers of other into the receiver.
+// The receiver and other may be sub-slices of the full storage as long as they
+// start on a register boundary.
+func (s denseStorage) unionRegisters(settings *settings, other denseStorage, numReg int) {
 
 	idx := 0
 	pos := 0
@@ -212,7 +271,7 @@ func (s denseStorage) union(settings *settings, other denseStorage) {
 	otherWord := other[idx]
 	computed := thisWord
 
-	mask := settings.mBitsMask << uint(64-settings.regwidth)
+	mask := settings.regwidthMask << uint(64-settings.regwidth)
 
 	for i := 0; i < numReg; i++ {
 
@@ -285,7 +344,7 @@ func (s denseStorage) union(settings *settings, other denseStorage) {
 
 			// prepare pos and mask for the next loop
 			pos = nLowerBits
-			mask = (settings.mBitsMask << uint(64-settings.regwidth)) >> uint(pos)
+			mask = (settings.regwidthMask << uint(64-settings.regwidth)) >> uint(pos)
 		}
 	}
 
@@ -294,6 +353,19 @@ func (s denseStorage) union(settings *settings, other denseStorage) {
 	}
 }
 
+// unionChunkSize returns the number of registers in each of the chunks that
+// can be passed independently to unionRegisters.  Each chunk starts on both a
+// word and a register boundary and contains at least minRegisters registers.
+// The final chunk may be smaller.
+func unionChunkSize(settings *settings, minRegisters int) int {
+
+	// registers and words align every 64 / gcd(64, regwidth) registers, and
+	// the gcd is the largest power of 2 that divides regwidth.
+	alignedRegisters := 64 / (settings.regwidth & -settings.regwidth)
+
+	return alignedRegisters * ((minRegisters + alignedRegisters - 1) / alignedRegisters)
+}
+
 // get extracts a single register value.  It is provided to enable union-ing two
 // dense storage instance with different Hll settings.
 func (s denseStorage) get(regnum, regwidth int) byte {
@@ -206,6 +206,29 @@ func Test_DenseGet(t *testing.T) {
 	}
 }
 
+// Test_DenseWideRegisters ensures that the indicator function and union read
+// the full register when regwidth is larger than log2m.
+func Test_DenseWideRegisters(t *testing.T) {
+	settings, err := Settings{Regwidth: 8, Log2m: 4}.toInternal()
+	require.NoError(t, err)
+
+	ds := newDenseStorage(settings)
+	sparse := make(sparseStorage)
+	for i := 0; i < 1<<uint(settings.log2m); i++ {
+		ds.setIfGreater(settings, i, byte(16*i+1))
+		sparse.setIfGreater(settings, i, byte(16*i+1))
+	}
+
+	sum, zeros := ds.indicator(settings)
+	expectedSum, expectedZeros := sparse.indicator(settings)
+	assert.Equal(t, expectedSum, sum)
+	assert.Equal(t, expectedZeros, zeros)
+
+	union := newDenseStorage(settings)
+	union.union(settings, ds)
+	assert.Equal(t, ds, union)
+}
+
 func assertElementsEqualDense(t *testing.T, hll1 Hll, hll2 Hll) {
 	if assertDense(t, hll1) && assertDense(t, hll2) {
 		assert.Equal(t, hll1.storage, hll2.storage)
@@ -0,0 +1,477 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+//go:generate go run gen_bias.go
+
+// Estimator selects the algorithm that is used to estimate the cardinality of
+// the probabilistic representations.  The explicit representation always
+// reports its exact cardinality regardless of the Estimator.
+type Estimator int
+
+const (
+	// ClassicEstimator is the estimator from the original HyperLogLog paper
+	// along with the "small range" (linear counting) and "large range"
+	// corrections.  It is the default and matches the estimates produced by
+	// the Java and PostgreSQL implementations.
+	ClassicEstimator Estimator = iota
+
+	// ImprovedEstimator is the improved raw estimator described by Otmar Ertl
+	// in "New cardinality estimation algorithms for HyperLogLog sketches"
+	// (https://arxiv.org/abs/1702.01284).  It is computed from the histogram
+	// of register values and does not require empirical bias correction or
+	// cutoffs between estimation regimes, so it avoids the bias that the
+	// classic estimator exhibits around the small range cutoff of 5m/2.
+	ImprovedEstimator
+
+	// MaximumLikelihoodEstimator is the maximum likelihood estimator described
+	// by Otmar Ertl in the same paper as ImprovedEstimator.  It solves for the
+	// cardinality that maximizes the likelihood of the observed register value
+	// histogram.  It has the lowest variance of the available estimators, which
+	// is most noticeable for small sketches, at the cost of an iterative
+	// computation.
+	MaximumLikelihoodEstimator
+
+	// HLLPlusPlusEstimator is the estimator from "HyperLogLog in Practice"
+	// (https://research.google.com/pubs/archive/40671.pdf) by Heule, Nunkesser,
+	// and Hall.  Raw estimates up to 5m are corrected using empirical bias
+	// tables for log2m 4 through 18, and linear counting is used whenever its
+	// result is below an empirically determined threshold rather than below
+	// 5m/2.  Larger values of log2m use the log2m 18 table and threshold scaled
+	// to the number of registers.
+	HLLPlusPlusEstimator
+
+	// numEstimators is the number of valid Estimator values.
+	numEstimators
+)
+
+// String returns the name of the Estimator.
+func (e Estimator) String() string {
+	switch e {
+	case ClassicEstimator:
+		return "ClassicEstimator"
+	case ImprovedEstimator:
+		return "ImprovedEstimator"
+	case MaximumLikelihoodEstimator:
+		return "MaximumLikelihoodEstimator"
+	case HLLPlusPlusEstimator:
+		return "HLLPlusPlusEstimator"
+	default:
+		return fmt.Sprintf("Estimator(%d)", int(e))
+	}
+}
+
+// Estimate is a cardinality estimate along with its uncertainty.  It is
+// returned by Hll.CardinalityEstimate.
+type Estimate struct {
+	// Value is the estimated cardinality.
+	Value float64
+
+	// StdError is the standard error of the estimate.
+	StdError float64
+
+	// Lower and Upper are the bounds of the confidence interval.  Lower is
+	// never less than zero.
+	Lower, Upper float64
+}
+
+// alphaInf is the limit of the alpha constant as m approaches infinity, which
+// is 1 / (2 * ln(2)).
+const alphaInf = 1 / (2 * math.Ln2)
+
+// estimate applies the provided estimator to the registers.  The result has
+// not been rounded.
+func estimate(e Estimator, settings *settings, s registers) float64 {
+	switch e {
+	case ClassicEstimator:
+		return classicEstimate(settings, s)
+	case ImprovedEstimator:
+		return improvedEstimate(settings, s.histogram(settings))
+	case MaximumLikelihoodEstimator:
+		return maximumLikelihoodEstimate(settings, s.histogram(settings))
+	case HLLPlusPlusEstimator:
+		return hllPlusPlusEstimate(settings, s)
+	default:
+		panic(fmt.Sprintf("unknown estimator: %v", e))
+	}
+}
+
+// standardError approximates the standard error of the estimate that the
+// estimator computed from the registers.  The error follows the regime that
+// produced the estimate: linear counting has the standard error derived by
+// Whang et al. in "A Linear-Time Probabilistic Counting Algorithm for Database
+// Applications", the HyperLogLog estimates have a relative error of
+// 1.04/sqrt(m), and both grow as the registers saturate, which happens sooner
+// for a smaller regwidth.
+//
+// Sparse storage sets too few registers for the relative error of the
+// HyperLogLog estimates to apply, so the error of linear counting is used
+// regardless of the estimator.
+func standardError(e Estimator, settings *settings, s registers, estimate float64) float64 {
+
+	if math.IsInf(estimate, 0) {
+		return math.Inf(1)
+	}
+
+	m := float64(int(1) << uint(settings.log2m))
+	sum, numberOfZeroes := s.indicator(settings)
+
+	relativeError := 1.04 / math.Sqrt(m)
+
+	if numberOfZeroes != 0 {
+		t := estimate / m
+		linearCountingError := math.Sqrt(m * (math.Exp(t) - t - 1))
+
+		var linearCounting bool
+		if _, ok := s.(sparseStorage); ok {
+			linearCounting = true
+		} else {
+			switch e {
+			case ClassicEstimator:
+				linearCounting = settings.alphaMSquared/sum < settings.smallEstimatorCutoff
+			case HLLPlusPlusEstimator:
+				linearCounting = m*math.Log(m/float64(numberOfZeroes)) <= hllPlusPlusThreshold(settings.log2m)
+			default:
+				// Ertl's estimators have no cutoff and are at least as
+				// accurate as linear counting.
+				linearCounting = linearCountingError < relativeError*estimate
+			}
+		}
+
+		if linearCounting {
+			return linearCountingError
+		}
+	}
+
+	switch e {
+	case ClassicEstimator, HLLPlusPlusEstimator:
+		if estimate <= settings.largeEstimatorCutoff {
+			return relativeError * estimate
+		}
+
+		// the large range correction magnifies the error of the raw estimate
+		// by its derivative, which is 1 / (1 - raw/2^L) = e^(estimate/2^L).
+		raw := -settings.twoToL * math.Expm1(-estimate/settings.twoToL)
+		return relativeError * raw * math.Exp(estimate/settings.twoToL)
+	default:
+		// Ertl's estimators treat the saturated registers like linear
+		// counting treats the empty ones, which magnifies the error in the
+		// same way as the large range correction but with a range limit of
+		// m*2^q.
+		limit := math.Ldexp(m, ertlQ(settings))
+		return relativeError * estimate * math.Exp(estimate/limit)
+	}
+}
+
+// classicEstimate computes the estimate from the original HyperLogLog paper
+// with the small and large range corrections.
+func classicEstimate(settings *settings, s registers) float64 {
+
+	sum, numberOfZeroes /*"V" in the paper*/ := s.indicator(settings)
+
+	// apply the estimate and correction to the indicator function
+	estimator := settings.alphaMSquared / sum
+
+	if (numberOfZeroes != 0) && (estimator < settings.smallEstimatorCutoff) {
+		// following documentation courtesy of the java implementation:
+		// The "small range correction" formula from the HyperLogLog
+		// algorithm. Only appropriate if both the estimator is smaller than
+		// (5/2) * m and there are still registers that have the zero value.
+		m := 1 << uint(settings.log2m)
+		return float64(m) * math.Log(float64(m)/float64(numberOfZeroes))
+	}
+
+	if estimator <= settings.largeEstimatorCutoff {
+		return estimator
+	}
+
+	// following documentation courtesy of the java implementation:
+	// The "large range correction" formula from the HyperLogLog algorithm,
+	// adapted for 64 bit hashes. Only appropriate for estimators whose
+	// value exceeds the calculated cutoff.
+	return -1 * settings.twoToL * math.Log(1.0-(estimator/settings.twoToL))
+}
+
+// hllPlusPlusThresholds are the cardinalities below which linear counting is
+// preferred over the bias corrected estimate for log2m 4 through 18, indexed by
+// log2m - 4.  The values are from the HyperLogLog++ paper.
+var hllPlusPlusThresholds = []float64{
+	10, 20, 40, 80, 220, 400, 900, 1800, 3100, 6500, 11500, 20000, 50000, 120000, 350000,
+}
+
+// hllPlusPlusNeighbors is the number of nearest raw estimates in the bias table
+// that are averaged to compute the bias.
+const hllPlusPlusNeighbors = 6
+
+// hllPlusPlusEstimate computes the HyperLogLog++ estimate (Figure 6 in the
+// paper).  Since the registers can't represent more than 64 bits of hash, the
+// large range correction is applied the same way as classicEstimate.
+func hllPlusPlusEstimate(settings *settings, s registers) float64 {
+
+	sum, numberOfZeroes := s.indicator(settings)
+	m := float64(int(1) << uint(settings.log2m))
+
+	estimator := settings.alphaMSquared / sum
+	if estimator <= 5*m {
+		estimator -= hllPlusPlusBias(settings.log2m, estimator)
+	}
+
+	if numberOfZeroes != 0 {
+		linearCount := m * math.Log(m/float64(numberOfZeroes))
+		if linearCount <= hllPlusPlusThreshold(settings.log2m) {
+			return linearCount
+		}
+	}
+
+	if estimator <= settings.largeEstimatorCutoff {
+		return estimator
+	}
+	return -1 * settings.twoToL * math.Log(1.0-(estimator/settings.twoToL))
+}
+
+// hllPlusPlusTableIndex returns the index into the HyperLogLog++ tables for
+// the provided log2m along with the factor by which the values in the table
+// must be scaled.
+func hllPlusPlusTableIndex(log2m int) (int, float64) {
+	index := log2m - minimumLog2mParam
+	if last := len(hllPlusPlusThresholds) - 1; index > last {
+		return last, float64(int(1) << uint(index-last))
+	}
+	return index, 1
+}
+
+// hllPlusPlusThreshold returns the linear counting threshold for log2m.
+func hllPlusPlusThreshold(log2m int) float64 {
+	index, scale := hllPlusPlusTableIndex(log2m)
+	return hllPlusPlusThresholds[index] * scale
+}
+
+// hllPlusPlusBias estimates the bias of the raw estimate by averaging the bias
+// of the nearest raw estimates in the table for log2m.
+func hllPlusPlusBias(log2m int, estimate float64) float64 {
+
+	index, scale := hllPlusPlusTableIndex(log2m)
+	rawEstimates := hllPlusPlusRawEstimates[index]
+	biases := hllPlusPlusBiases[index]
+	estimate /= scale
+
+	// grow a window around the insertion point by taking whichever neighbor
+	// is closer to the estimate.
+	hi := sort.SearchFloat64s(rawEstimates, estimate)
+	lo := hi
+	for hi-lo < hllPlusPlusNeighbors && hi-lo < len(rawEstimates) {
+		if lo == 0 || (hi < len(rawEstimates) && rawEstimates[hi]-estimate < estimate-rawEstimates[lo-1]) {
+			hi++
+		} else {
+			lo--
+		}
+	}
+
+	bias := 0.0
+	for _, b := range biases[lo:hi] {
+		bias += b
+	}
+	return bias / float64(hi-lo) * scale
+}
+
+// improvedEstimate computes Ertl's improved raw estimate (Algorithm 6 in the
+// paper) from the histogram of register values.
+func improvedEstimate(settings *settings, counts []int) float64 {
+
+	m := float64(int(1) << uint(settings.log2m))
+	q := ertlQ(settings)
+
+	z := m * ertlTau(1-float64(counts[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(counts[k]))
+	}
+	z += m * ertlSigma(float64(counts[0])/m)
+
+	return alphaInf * m * m / z
+}
+
+// maximumLikelihoodEstimate computes Ertl's maximum likelihood estimate
+// (Algorithm 8 in the paper) from the histogram of register values.  It uses
+// the secant method to find the root of the derivative of the log-likelihood
+// function.
+func maximumLikelihoodEstimate(settings *settings, counts []int) float64 {
+
+	m := int(1) << uint(settings.log2m)
+	q := ertlQ(settings)
+
+	// all registers saturated...the likelihood has no maximum.
+	if counts[q+1] == m {
+		return math.Inf(1)
+	}
+
+	kMin := 0
+	for counts[kMin] == 0 {
+		kMin++
+	}
+	kMinPrime := kMin
+	if kMinPrime < 1 {
+		kMinPrime = 1
+	}
+
+	kMax := q + 1
+	for counts[kMax] == 0 {
+		kMax--
+	}
+	kMaxPrime := kMax
+	if kMaxPrime > q {
+		kMaxPrime = q
+	}
+
+	// all registers are zero.
+	if kMax == 0 {
+		return 0
+	}
+
+	z := 0.0
+	for k := kMaxPrime; k >= kMinPrime; k-- {
+		z = 0.5*z + float64(counts[k])
+	}
+	z = math.Ldexp(z, -kMinPrime)
+
+	cPrime := counts[q+1]
+	if q >= 1 {
+		cPrime += counts[kMaxPrime]
+	}
+
+	// the derivative of the log-likelihood is zero where g(x) = m', where
+	//
+	//   g(x) = a*x + sum(C[k] * h(x / 2^min(k, q))) for k in 1..q+1
+	//
+	// and h(x) = 1 - x/(e^x - 1).  g is increasing and concave, and since
+	// 0 <= h(x) <= x/2, m'/(a + b/2) is a lower bound for the solution where b
+	// accumulates the C[k] / 2^min(k, q) terms.  when b > 1.5a, m'/b *
+	// log(1 + b/a) is a tighter lower bound.  starting from a lower bound, the
+	// secant method through (0, 0) converges monotonically from below.
+	gPrev := 0.0
+	a := z + float64(counts[0])
+	b := z + math.Ldexp(float64(counts[q+1]), -q)
+	mPrime := float64(m - counts[0])
+
+	var x float64
+	if b <= 1.5*a {
+		x = mPrime / (a + 0.5*b)
+	} else {
+		x = mPrime / b * math.Log1p(b/a)
+	}
+
+	// iterate until the change is small relative to the standard error.
+	relativeErrorLimit := 1e-2 / math.Sqrt(float64(m))
+
+	deltaX := x
+	for deltaX > x*relativeErrorLimit {
+
+		// NOTE : Frexp returns the exponent such that x = frac * 2^exp where
+		//        frac is in [0.5, 1), which is floor(log2(x)) + 1.
+		_, exp := math.Frexp(x)
+		kappa := exp + 1
+
+		xPrime := math.Ldexp(x, -maxInt(kMaxPrime, kappa)-1)
+		xPrime2 := xPrime * xPrime
+		h := xPrime - xPrime2/3 + (xPrime2*xPrime2)*(1.0/45-xPrime2/472.5)
+
+		for k := kappa - 1; k >= kMaxPrime; k-- {
+			hPrime := 1 - h
+			h = (xPrime + h*hPrime) / (xPrime + hPrime)
+			xPrime += xPrime
+		}
+
+		g := float64(cPrime) * h
+		for k := kMaxPrime - 1; k >= kMinPrime; k-- {
+			hPrime := 1 - h
+			h = (xPrime + h*hPrime) / (xPrime + hPrime)
+			xPrime += xPrime
+			g += float64(counts[k]) * h
+		}
+		g += x * a
+
+		if gPrev < g && g <= mPrime {
+			deltaX *= (g - mPrime) / (gPrev - g)
+		} else {
+			deltaX = 0
+		}
+
+		x += deltaX
+		gPrev = g
+	}
+
+	return float64(m) * x
+}
+
+// ertlQ returns the number of hash bits that are available to determine the
+// register value, which is "q" in Ertl's paper.  A register value of q+1
+// indicates that all q bits were zero.
+//
+// Register values are capped at 2^regwidth - 1 by pwMaxMask, which makes q one
+// less than that.  However, only 64 - log2m bits remain after the register
+// index has been removed from the hash.  When that is the limiting factor, the
+// case where all of the bits are zero is discarded by AddRaw, so the count for
+// q+1 is always zero.
+func ertlQ(settings *settings) int {
+	q := (1 << uint(settings.regwidth)) - 2
+	if remaining := 64 - settings.log2m; remaining < q {
+		q = remaining
+	}
+	return q
+}
+
+// clampHistogram moves the counts of register values above q+1 into q+1.  No
+// hash produces such values, but a corrupt serialized Hll can hold them, and
+// they would otherwise be ignored by the improved estimator and leave no
+// maximum register value for the maximum likelihood estimator to find.
+func clampHistogram(settings *settings, counts []int) []int {
+	q := ertlQ(settings)
+	for k := q + 2; k < len(counts); k++ {
+		counts[q+1] += counts[k]
+		counts[k] = 0
+	}
+	return counts
+}
+
+// ertlSigma is the sigma function from Ertl's paper, which is used to account
+// for the registers whose value is zero.
+func ertlSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		zPrev := z
+		z += x * y
+		y += y
+		if z == zPrev {
+			return z
+		}
+	}
+}
+
+// ertlTau is the tau function from Ertl's paper, which is used to account for
+// the registers whose value is saturated.
+func ertlTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		zPrev := z
+		y *= 0.5
+		z -= math.Pow(1-x, 2) * y
+		if z == zPrev {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,530 @@
+package hll
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test_Estimator_Accuracy ensures that the histogram based estimators are
+// within three standard errors of the true cardinality across the small, mid,
+// and normal ranges, including the transition at 5m/2 where the classic
+// estimator switches from linear counting.
+func Test_Estimator_Accuracy(t *testing.T) {
+
+	for _, estimator := range []Estimator{ImprovedEstimator, MaximumLikelihoodEstimator, HLLPlusPlusEstimator} {
+		for _, log2m := range []int{10, 11, 12} {
+			for _, regwidth := range []int{4, 5, 6} {
+
+				settings := Settings{Log2m: log2m, Regwidth: regwidth, ExplicitThreshold: 0, SparseEnabled: true}
+				m := 1 << uint(settings.Log2m)
+				tolerance := 3 * 1.04 / math.Sqrt(float64(m))
+
+				for _, n := range []int{100, 1000, 5 * m / 2, 10000, 100000} {
+					t.Run(fmt.Sprintf("%v/log2m-%d/regwidth-%d/n-%d", estimator, log2m, regwidth, n), func(t *testing.T) {
+
+						r := rand.New(rand.NewSource(int64(n)))
+
+						hll := newHll(t, settings)
+						for i := 0; i < n; i++ {
+							hll.AddRaw(r.Uint64())
+						}
+
+						estimate := float64(hll.CardinalityWith(estimator))
+						assert.InDelta(t, 1.0, estimate/float64(n), tolerance)
+					})
+				}
+			}
+		}
+	}
+}
+
+// Test_MaximumLikelihoodEstimator_Variance ensures that the maximum likelihood
+// estimator has a lower mean squared error than the classic estimator for a
+// small sketch.
+func Test_MaximumLikelihoodEstimator_Variance(t *testing.T) {
+
+	settings := Settings{Log2m: 10, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false}
+	r := rand.New(rand.NewSource(1234567890))
+
+	const runs = 200
+	const n = 3000 // in the range where the classic estimator is most biased.
+
+	var classicError, mleError float64
+	for i := 0; i < runs; i++ {
+		hll := newHll(t, settings)
+		for j := 0; j < n; j++ {
+			hll.AddRaw(r.Uint64())
+		}
+
+		classicError += math.Pow(float64(hll.CardinalityWith(ClassicEstimator))-n, 2)
+		mleError += math.Pow(float64(hll.CardinalityWith(MaximumLikelihoodEstimator))-n, 2)
+	}
+
+	assert.True(t, mleError < classicError, "mle: %f, classic: %f", mleError, classicError)
+}
+
+// Test_HLLPlusPlusEstimator_Bias ensures that the bias correction reduces the
+// mean error of the classic estimator between 5m/2 and 5m.
+func Test_HLLPlusPlusEstimator_Bias(t *testing.T) {
+
+	settings := Settings{Log2m: 8, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false}
+	r := rand.New(rand.NewSource(1234567890))
+
+	const runs = 500
+	const n = 1000 // about 4m
+
+	var classicError, hllPlusPlusError float64
+	for i := 0; i < runs; i++ {
+		hll := newHll(t, settings)
+		for j := 0; j < n; j++ {
+			hll.AddRaw(r.Uint64())
+		}
+
+		classicError += float64(hll.CardinalityWith(ClassicEstimator)) - n
+		hllPlusPlusError += float64(hll.CardinalityWith(HLLPlusPlusEstimator)) - n
+	}
+
+	assert.True(t, math.Abs(hllPlusPlusError) < math.Abs(classicError), "hll++: %f, classic: %f", hllPlusPlusError/runs, classicError/runs)
+}
+
+// Test_HLLPlusPlusEstimator_LinearCounting ensures that linear counting is used
+// below the threshold.
+func Test_HLLPlusPlusEstimator_LinearCounting(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: true}
+	r := rand.New(rand.NewSource(1234567890))
+
+	hll := newHll(t, settings)
+	for i := 0; i < 1000; i++ {
+		hll.AddRaw(r.Uint64())
+	}
+
+	// the classic estimator also uses linear counting here.
+	assert.Equal(t, hll.CardinalityWith(ClassicEstimator), hll.CardinalityWith(HLLPlusPlusEstimator))
+
+	empty := newHll(t, settings)
+	assert.Equal(t, uint64(0), empty.CardinalityWith(HLLPlusPlusEstimator))
+}
+
+func Test_HLLPlusPlusTables(t *testing.T) {
+
+	numLog2m := 18 - minimumLog2mParam + 1
+	require.Len(t, hllPlusPlusThresholds, numLog2m)
+	require.Len(t, hllPlusPlusRawEstimates, numLog2m)
+	require.Len(t, hllPlusPlusBiases, numLog2m)
+
+	for i := range hllPlusPlusRawEstimates {
+		require.Equal(t, len(hllPlusPlusRawEstimates[i]), len(hllPlusPlusBiases[i]))
+		require.True(t, len(hllPlusPlusRawEstimates[i]) >= hllPlusPlusNeighbors)
+		assert.True(t, sort.Float64sAreSorted(hllPlusPlusRawEstimates[i]), "log2m %d", i+minimumLog2mParam)
+	}
+
+	// the average of the 6 nearest entries.
+	rawEstimates, biases := hllPlusPlusRawEstimates[4], hllPlusPlusBiases[4]
+	expected := 0.0
+	for _, b := range biases[:hllPlusPlusNeighbors] {
+		expected += b
+	}
+	assert.InDelta(t, expected/hllPlusPlusNeighbors, hllPlusPlusBias(8, rawEstimates[0]-1), 1e-9)
+	assert.InDelta(t, expected/hllPlusPlusNeighbors, hllPlusPlusBias(8, rawEstimates[2]), 1e-9)
+
+	// larger log2m are scaled from the log2m 18 table.
+	last := len(hllPlusPlusRawEstimates) - 1
+	assert.InDelta(t, 4*hllPlusPlusBias(18, 1000000), hllPlusPlusBias(20, 4000000), 1e-6)
+	assert.Equal(t, 4*hllPlusPlusThresholds[last], hllPlusPlusThreshold(20))
+}
+
+func Test_CardinalityEstimate(t *testing.T) {
+
+	settings := Settings{Log2m: 10, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	hll := newHll(t, settings)
+	estimate, err := hll.CardinalityEstimate(0.95)
+	require.NoError(t, err)
+	assert.Equal(t, Estimate{}, estimate)
+
+	for _, confidence := range []float64{0, 1, -0.5, 1.5, math.NaN()} {
+		_, err := hll.CardinalityEstimate(confidence)
+		assert.Error(t, err, "confidence %v", confidence)
+	}
+
+	// explicit is exact.
+	for i := 0; i < 10; i++ {
+		hll.AddRaw(uint64(i + 1))
+	}
+	assertExplicit(t, hll)
+	estimate, err = hll.CardinalityEstimate(0.99)
+	require.NoError(t, err)
+	assert.Equal(t, Estimate{Value: 10, Lower: 10, Upper: 10}, estimate)
+}
+
+// Test_CardinalityEstimate_Coverage ensures that the confidence intervals
+// contain the true cardinality at approximately the requested rate in both the
+// linear counting and normal ranges.
+func Test_CardinalityEstimate_Coverage(t *testing.T) {
+
+	settings := Settings{Log2m: 10, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: true}
+
+	for _, n := range []int{500, 20000} {
+		t.Run(fmt.Sprintf("n-%d", n), func(t *testing.T) {
+
+			r := rand.New(rand.NewSource(int64(n)))
+
+			const runs = 200
+			covered := 0
+			var stdError float64
+			for i := 0; i < runs; i++ {
+				hll := newHll(t, settings)
+				for j := 0; j < n; j++ {
+					hll.AddRaw(r.Uint64())
+				}
+
+				estimate, err := hll.CardinalityEstimate(0.95)
+				require.NoError(t, err)
+				assert.True(t, estimate.Lower <= estimate.Value && estimate.Value <= estimate.Upper)
+				if estimate.Lower <= float64(n) && float64(n) <= estimate.Upper {
+					covered++
+				}
+				stdError = estimate.StdError
+			}
+
+			assert.InDelta(t, 0.95, float64(covered)/runs, 0.05)
+
+			// linear counting is more accurate than 1.04/sqrt(m).
+			if n == 500 {
+				assert.True(t, stdError < 1.04/32*float64(n), "stdError: %f", stdError)
+			}
+		})
+	}
+}
+
+// Test_CardinalityEstimate_LargeRange ensures that the error grows once the
+// large range correction is applied.
+func Test_CardinalityEstimate_LargeRange(t *testing.T) {
+
+	settings, err := Settings{Log2m: 10, Regwidth: 3}.toInternal()
+	require.NoError(t, err)
+
+	s := newDenseStorage(settings)
+	for i := 0; i < 1<<uint(settings.log2m); i++ {
+		s.setIfGreater(settings, i, byte(1+i%6))
+	}
+
+	for e := Estimator(0); e < numEstimators; e++ {
+		value := estimate(e, settings, s)
+		assert.True(t, standardError(e, settings, s, value) > 1.04/32*value, "%v", e)
+		assert.True(t, math.IsInf(standardError(e, settings, s, math.Inf(1)), 1), "%v", e)
+	}
+	require.True(t, estimate(ClassicEstimator, settings, s) > settings.largeEstimatorCutoff)
+}
+
+// Test_CardinalityEstimate_Estimators ensures that the error follows the regime
+// of each estimator and representation.
+func Test_CardinalityEstimate_Estimators(t *testing.T) {
+
+	settings, err := Settings{Log2m: 10, Regwidth: 5, SparseEnabled: true}.toInternal()
+	require.NoError(t, err)
+
+	r := rand.New(rand.NewSource(1))
+
+	// sparse storage has the error of linear counting for every estimator.
+	sparse := sparseStorage{}
+	for i := 0; i < 100; i++ {
+		sparse.setIfGreater(settings, r.Intn(1<<10), byte(1+r.Intn(5)))
+	}
+	for e := Estimator(0); e < numEstimators; e++ {
+		value := estimate(e, settings, sparse)
+		assert.True(t, standardError(e, settings, sparse, value) < 1.04/32*value, "%v", e)
+	}
+
+	// at about m values, the classic estimator still uses linear counting, but
+	// the HyperLogLog++ threshold has been passed.
+	dense := newDenseStorage(settings)
+	for i := 0; i < 1000; i++ {
+		index, value := settings.register(r.Uint64())
+		dense.setIfGreater(settings, index, value)
+	}
+	value := estimate(HLLPlusPlusEstimator, settings, dense)
+	assert.InDelta(t, 1.04/32*value, standardError(HLLPlusPlusEstimator, settings, dense, value), 1e-9)
+	value = estimate(ClassicEstimator, settings, dense)
+	assert.True(t, standardError(ClassicEstimator, settings, dense, value) < 1.04/32*value)
+}
+
+// Test_Cardinality_Saturated ensures that an estimate that is infinite, NaN, or
+// too large for a uint64 is clamped rather than converted, since the result of
+// the conversion depends on the platform.
+func Test_Cardinality_Saturated(t *testing.T) {
+
+	for _, regwidth := range []int{3, 5} {
+		hll := newHll(t, Settings{Log2m: 10, Regwidth: regwidth, ExplicitThreshold: 0})
+		for i := 0; i < 1<<10; i++ {
+			hll.AddRaw(uint64(i) | 1<<63)
+		}
+
+		for _, estimator := range []Estimator{ClassicEstimator, ImprovedEstimator, MaximumLikelihoodEstimator, HLLPlusPlusEstimator} {
+			assert.Equal(t, uint64(math.MaxUint64), hll.CardinalityWith(estimator), "regwidth %d, %v", regwidth, estimator)
+		}
+	}
+}
+
+// Test_CardinalityEstimate_Saturated ensures that the bounds are defined when
+// every register is saturated and the estimate is infinite.
+func Test_CardinalityEstimate_Saturated(t *testing.T) {
+
+	hll := newHll(t, Settings{Log2m: 10, Regwidth: 3, ExplicitThreshold: 0, Estimator: MaximumLikelihoodEstimator})
+	for i := 0; i < 1<<10; i++ {
+		hll.AddRaw(uint64(i) | 1<<63)
+	}
+
+	estimate, err := hll.CardinalityEstimate(0.95)
+	require.NoError(t, err)
+	assert.True(t, math.IsInf(estimate.Value, 1))
+	assert.True(t, math.IsInf(estimate.StdError, 1))
+	assert.Equal(t, 0.0, estimate.Lower)
+	assert.True(t, math.IsInf(estimate.Upper, 1))
+}
+
+// Test_MaximumLikelihoodEstimator_EdgeCases exercises histograms with empty
+// and saturated registers.
+func Test_MaximumLikelihoodEstimator_EdgeCases(t *testing.T) {
+
+	settings, err := Settings{Log2m: 4, Regwidth: 4}.toInternal()
+	require.NoError(t, err)
+
+	m := 1 << uint(settings.log2m)
+	q := ertlQ(settings)
+
+	// all registers zero.
+	counts := make([]int, 1<<uint(settings.regwidth))
+	counts[0] = m
+	assert.Equal(t, 0.0, maximumLikelihoodEstimate(settings, counts))
+
+	// all registers saturated.
+	counts = make([]int, 1<<uint(settings.regwidth))
+	counts[q+1] = m
+	assert.True(t, math.IsInf(maximumLikelihoodEstimate(settings, counts), 1))
+
+	// one register set to one...linear counting gives m * ln(m / (m - 1)).
+	counts = make([]int, 1<<uint(settings.regwidth))
+	counts[0] = m - 1
+	counts[1] = 1
+	assert.InDelta(t, 1.0, maximumLikelihoodEstimate(settings, counts), 0.05)
+
+	// some registers saturated.
+	counts = make([]int, 1<<uint(settings.regwidth))
+	counts[q] = m / 2
+	counts[q+1] = m / 2
+	assert.False(t, math.IsInf(maximumLikelihoodEstimate(settings, counts), 0))
+	assert.True(t, maximumLikelihoodEstimate(settings, counts) > 0)
+}
+
+// Test_MaximumLikelihoodEstimator_InitialGuess compares the estimate to the
+// root of the derivative of the log-likelihood found by bisection.  The
+// histograms cover both of the lower bounds that the secant method starts from.
+func Test_MaximumLikelihoodEstimator_InitialGuess(t *testing.T) {
+
+	settings, err := Settings{Log2m: 4, Regwidth: 2}.toInternal()
+	require.NoError(t, err)
+
+	m := 1 << uint(settings.log2m)
+	q := ertlQ(settings)
+
+	// g(x) as described in maximumLikelihoodEstimate, which equals m' at the
+	// maximum.
+	g := func(counts []int, x float64) float64 {
+		a := float64(counts[0])
+		for k := 1; k <= q; k++ {
+			a += math.Ldexp(float64(counts[k]), -k)
+		}
+		sum := a * x
+		for k := 1; k <= q+1; k++ {
+			xk := math.Ldexp(x, -k)
+			if k > q {
+				xk = math.Ldexp(x, -q)
+			}
+			sum += float64(counts[k]) * (1 - xk/math.Expm1(xk))
+		}
+		return sum
+	}
+
+	for _, counts := range [][]int{
+		{4, 8, 3, 1},  // b <= 1.5a.
+		{0, 1, 0, 15}, // b > 1.5a.
+		{1, 0, 1, 14}, // b > 1.5a.
+	} {
+		mPrime := float64(m - counts[0])
+
+		lo, hi := 0.0, 1.0
+		for g(counts, hi) < mPrime {
+			hi *= 2
+		}
+		for i := 0; i < 100; i++ {
+			if mid := (lo + hi) / 2; g(counts, mid) < mPrime {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+
+		assert.InEpsilon(t, float64(m)*lo, maximumLikelihoodEstimate(settings, counts), 1e-3, "%v", counts)
+	}
+}
+
+// Test_ImprovedEstimator_Settings ensures that the Estimator in the Settings is
+// used by Cardinality.
+func Test_ImprovedEstimator_Settings(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	improvedSettings := settings
+	improvedSettings.Estimator = ImprovedEstimator
+
+	classic := newHll(t, settings)
+	improved := newHll(t, improvedSettings)
+	assert.Equal(t, ImprovedEstimator, improved.Settings().Estimator)
+
+	r := rand.New(rand.NewSource(1234567890))
+
+	// the explicit representation is exact regardless of the estimator.
+	for i := 0; i < 10; i++ {
+		value := r.Uint64()
+		classic.AddRaw(value)
+		improved.AddRaw(value)
+	}
+	assertExplicit(t, improved)
+	assert.Equal(t, uint64(10), improved.Cardinality())
+	assert.Equal(t, uint64(10), improved.CardinalityWith(ClassicEstimator))
+
+	for i := 0; i < 5000; i++ {
+		value := r.Uint64()
+		classic.AddRaw(value)
+		improved.AddRaw(value)
+	}
+	assertDense(t, improved)
+	assert.Equal(t, classic.CardinalityWith(ImprovedEstimator), improved.Cardinality())
+	assert.Equal(t, classic.Cardinality(), improved.CardinalityWith(ClassicEstimator))
+
+	// StrictUnion doesn't consider the estimator.
+	require.NoError(t, classic.StrictUnion(improved))
+
+	// invalid estimators are rejected.
+	improvedSettings.Estimator = numEstimators
+	_, err := NewHll(improvedSettings)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Estimator is invalid")
+
+	assert.Panics(t, func() { classic.CardinalityWith(Estimator(-1)) })
+}
+
+// Test_ImprovedEstimator_NoRegistersSet ensures that a probabilistic Hll with
+// no registers set has an estimated cardinality of zero.
+func Test_ImprovedEstimator_NoRegistersSet(t *testing.T) {
+
+	for _, hexEncoded := range []string{"138b7f" /*sparse*/, "148b7f" + strings.Repeat("00", 1280) /*dense*/} {
+		bytes, err := hex.DecodeString(hexEncoded)
+		require.NoError(t, err)
+
+		hll, err := FromBytes(bytes)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), hll.CardinalityWith(ImprovedEstimator))
+	}
+}
+
+// Test_Histogram ensures that the sparse and dense representations produce the
+// same register histogram.
+func Test_Histogram(t *testing.T) {
+
+	for _, settings := range []Settings{
+		{Log2m: 11, Regwidth: 5, SparseEnabled: true},
+		{Log2m: 4, Regwidth: 8, SparseEnabled: true},
+		{Log2m: 13, Regwidth: 3, SparseEnabled: true},
+	} {
+		t.Run(fmt.Sprintf("%+v", settings), func(t *testing.T) {
+
+			internal, err := settings.toInternal()
+			require.NoError(t, err)
+
+			r := rand.New(rand.NewSource(1234567890))
+
+			sparse := make(sparseStorage)
+			for i := 0; i < 100; i++ {
+				if i, pW := internal.register(r.Uint64()); pW > 0 {
+					sparse.setIfGreater(internal, i, pW)
+				}
+			}
+
+			dense := sparseToDense(internal, sparse)
+
+			counts := sparse.histogram(internal)
+			assert.Equal(t, counts, dense.histogram(internal))
+			assert.Equal(t, 1<<uint(settings.Regwidth), len(counts))
+
+			total := 0
+			for _, c := range counts {
+				total += c
+			}
+			assert.Equal(t, 1<<uint(settings.Log2m), total)
+		})
+	}
+}
+
+// Test_Histogram_Clamped ensures that register values above q+1, which only a
+// corrupt serialized Hll can hold, are counted as q+1 so that the estimators
+// don't panic.
+func Test_Histogram_Clamped(t *testing.T) {
+
+	// log2m 11 and regwidth 6 allow values up to 63, but q+1 is 54.
+	bytes := append([]byte{0x14, 0xab, 0x7f}, make([]byte, 1536)...)
+	for i := 3; i < len(bytes); i++ {
+		bytes[i] = 0xff
+	}
+
+	h, err := FromBytes(bytes)
+	require.NoError(t, err)
+
+	counts := h.storage.(registers).histogram(h.settings)
+	assert.Equal(t, 2048, counts[ertlQ(h.settings)+1])
+	assert.Equal(t, 0, counts[63])
+
+	sparse := newHll(t, Settings{Log2m: 11, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	sparse.storage = sparseStorage{0: 63, 1: 54, 2: 1}
+	counts = sparse.storage.(registers).histogram(sparse.settings)
+	assert.Equal(t, 2, counts[54])
+	assert.Equal(t, 0, counts[63])
+
+	for estimator := Estimator(0); estimator < numEstimators; estimator++ {
+		assert.NotPanics(t, func() { h.CardinalityWith(estimator) }, "%v", estimator)
+		assert.NotPanics(t, func() { sparse.CardinalityWith(estimator) }, "%v", estimator)
+	}
+	assert.True(t, math.IsInf(maximumLikelihoodEstimate(h.settings, h.storage.(registers).histogram(h.settings)), 1))
+}
+
+func Test_ertlQ(t *testing.T) {
+
+	// limited by the register width.
+	settings, err := Settings{Log2m: 11, Regwidth: 5}.toInternal()
+	require.NoError(t, err)
+	assert.Equal(t, 30, ertlQ(settings))
+
+	// limited by the number of bits remaining in the hash.
+	settings, err = Settings{Log2m: 11, Regwidth: 6}.toInternal()
+	require.NoError(t, err)
+	assert.Equal(t, 53, ertlQ(settings))
+}
+
+func Test_ertlSigmaTau(t *testing.T) {
+	assert.Equal(t, 0.0, ertlSigma(0))
+	assert.True(t, math.IsInf(ertlSigma(1), 1))
+	assert.Equal(t, 0.0, ertlTau(0))
+	assert.Equal(t, 0.0, ertlTau(1))
+
+	// sigma(x) = x + sum(x^(2^k) * 2^(k-1)) for k >= 1
+	assert.InDelta(t, 0.5+0.25*1+0.0625*2+0.00390625*4+0.0000152587890625*8, ertlSigma(0.5), 1e-8)
+}
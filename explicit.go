@@ -22,6 +22,22 @@ func (s explicitStorage) sizeInBytes(settings *settings) int {
 // ending values. Per the storage spec, they are sorted as signed 64 bit
 // integers in ascending order.
 func (s explicitStorage) writeBytes(settings *settings, bytes []byte) {
+	for i, value := range s.sortedValues() {
+		pos := i * 8
+		binary.BigEndian.PutUint64(bytes[pos:pos+8], uint64(value))
+	}
+}
+
+// writeTo writes the same values as writeBytes to the chunkWriter.
+func (s explicitStorage) writeTo(settings *settings, w *chunkWriter) {
+	for _, value := range s.sortedValues() {
+		binary.BigEndian.PutUint64(w.next(8), uint64(value))
+	}
+}
+
+// sortedValues returns the raw values sorted as signed 64 bit integers, which
+// is the order required by the storage spec.
+func (s explicitStorage) sortedValues() []int64 {
 
 	// NOTE : the postgres hll implementation will reject a serialized value that is not in order.
 	sortedValues := make([]int64, 0, len(s))
@@ -31,10 +47,7 @@ func (s explicitStorage) writeBytes(settings *settings, bytes []byte) {
 
 	sort.Slice(sortedValues, func(i, j int) bool { return sortedValues[i] < sortedValues[j] })
 
-	for i, value := range sortedValues {
-		pos := i * 8
-		binary.BigEndian.PutUint64(bytes[pos:pos+8], uint64(value))
-	}
+	return sortedValues
 }
 
 // fromBytes reads big endian 8 byte values from the byte slice.  It will return
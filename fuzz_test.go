@@ -0,0 +1,233 @@
+//go:build go1.18
+
+package hll
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzMaxLog2m limits the Hlls exercised by the fuzz targets.  Larger Hlls are
+// valid, but promoting them to dense storage legitimately allocates up to 2^31
+// registers, which would exhaust the fuzzer's memory.
+const fuzzMaxLog2m = 16
+
+// fuzzSeedsPerFile is the number of Hlls taken from each integration test file
+// for the seed corpus.
+const fuzzSeedsPerFile = 25
+
+func FuzzFromBytes(f *testing.F) {
+
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+
+		h, err := FromBytes(data)
+		strict, strictErr := FromBytesStrict(data)
+
+		if err != nil {
+			require.Error(t, strictErr, "strict decoding accepted input rejected by FromBytes")
+			return
+		}
+
+		// input accepted by the strict decoder is canonical.
+		if strictErr == nil {
+			require.Equal(t, data, strict.ToBytes())
+		}
+
+		if h.settings.log2m > fuzzMaxLog2m {
+			return
+		}
+
+		exercise(t, h)
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+
+		h, err := FromBytes(data)
+		if err != nil || h.settings.log2m > fuzzMaxLog2m {
+			return
+		}
+
+		encoded := h.ToBytes()
+
+		decoded, err := FromBytes(encoded)
+		require.NoError(t, err)
+		require.Equal(t, encoded, decoded.ToBytes())
+		require.Equal(t, registerValues(h), registerValues(decoded))
+		require.Equal(t, h.Cardinality(), decoded.Cardinality())
+
+		// re-encoding always produces canonical output.
+		requireStrict(t, decoded, encoded)
+
+		var buf bytes.Buffer
+		_, err = h.WriteTo(&buf)
+		require.NoError(t, err)
+		require.Equal(t, encoded, buf.Bytes())
+		require.Equal(t, encoded, h.AppendBytes(nil))
+
+		read, err := ReadHll(&buf)
+		require.NoError(t, err)
+		require.Equal(t, encoded, read.ToBytes())
+
+		require.NoError(t, read.Decode(data))
+		require.Equal(t, encoded, read.ToBytes())
+	})
+}
+
+func FuzzUnion(f *testing.F) {
+
+	seeds := fuzzSeeds(f)
+	for i := range seeds {
+		f.Add(seeds[i], seeds[(i*7+1)%len(seeds)])
+	}
+
+	f.Fuzz(func(t *testing.T, a, b []byte) {
+
+		dst, err := FromBytes(a)
+		if err != nil || dst.settings.log2m > fuzzMaxLog2m {
+			return
+		}
+		src, err := FromBytes(b)
+		if err != nil || src.settings.log2m > fuzzMaxLog2m {
+			return
+		}
+
+		// Union modifies the receiver's storage, so each operation gets its
+		// own copy.
+		union, _ := FromBytes(a)
+		union.Union(src)
+
+		unionBytes, _ := FromBytes(a)
+		require.NoError(t, UnionBytes(&unionBytes, b))
+		require.Equal(t, union.ToBytes(), unionBytes.ToBytes())
+
+		strict, _ := FromBytes(a)
+		if err := strict.StrictUnion(src); err == nil {
+			require.Equal(t, union.ToBytes(), strict.ToBytes())
+		}
+
+		all, err := UnionAll(dst, src)
+		if err == nil {
+			assert.Equal(t, registerValues(union), registerValues(all))
+		}
+
+		_, _ = IntersectionCardinality(dst, src)
+		_, _ = Jaccard(dst, src)
+
+		exercise(t, union)
+	})
+}
+
+// exercise runs the operations that read and modify the registers of h to
+// ensure that none of them panic.
+func exercise(t *testing.T, h Hll) {
+
+	for estimator := Estimator(0); estimator < numEstimators; estimator++ {
+		h.CardinalityWith(estimator)
+	}
+	_, _ = h.CardinalityEstimate(0.95)
+
+	other, err := FromBytes(h.ToBytes())
+	require.NoError(t, err)
+
+	h.Union(other)
+	for i := uint64(0); i < 64; i++ {
+		h.AddRaw((i + 1) * 0x9e3779b97f4a7c15)
+	}
+	h.Cardinality()
+
+	requireStrict(t, h, h.ToBytes())
+}
+
+// requireStrict ensures that FromBytesStrict accepts the serialized Hll unless
+// it holds a register value that no hash can produce.  FromBytes accepts those,
+// and the operations carry them through to the output.
+func requireStrict(t *testing.T, h Hll, bytes []byte) {
+
+	_, err := FromBytesStrict(bytes)
+
+	for _, value := range registerValues(h) {
+		if uint64(value) > maxRegisterValue(h.settings) {
+			require.Equal(t, ErrRegisterOutOfRange, err)
+			return
+		}
+	}
+
+	require.NoError(t, err)
+}
+
+// fuzzSeeds returns serialized Hlls drawn from the integration tests.
+func fuzzSeeds(tb testing.TB) [][]byte {
+
+	var seeds [][]byte
+
+	suites, err := ioutil.ReadDir("integration_tests")
+	require.NoError(tb, err)
+
+	for _, suite := range suites {
+
+		if suite.Name() == "hash" {
+			continue
+		}
+
+		suiteDir := "integration_tests/" + suite.Name()
+		files, err := ioutil.ReadDir(suiteDir)
+		require.NoError(tb, err)
+
+		for _, file := range files {
+			seeds = append(seeds, fuzzSeedsFromFile(tb, suiteDir+"/"+file.Name())...)
+		}
+	}
+
+	return seeds
+}
+
+// fuzzSeedsFromFile returns the serialized Hlls from the first lines of the
+// integration test file.
+func fuzzSeedsFromFile(tb testing.TB, path string) [][]byte {
+
+	reader, err := os.Open(path)
+	require.NoError(tb, err)
+	defer reader.Close()
+
+	decompressed, err := gzip.NewReader(reader)
+	require.NoError(tb, err)
+	defer decompressed.Close()
+
+	var seeds [][]byte
+
+	scanner := bufio.NewScanner(decompressed)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() && len(seeds) < fuzzSeedsPerFile {
+		for _, column := range strings.Split(scanner.Text(), ",") {
+			if !strings.HasPrefix(column, `\x`) {
+				continue
+			}
+			seed, err := hex.DecodeString(column[2:])
+			require.NoError(tb, err)
+			seeds = append(seeds, seed)
+		}
+	}
+	require.NoError(tb, scanner.Err())
+
+	return seeds
+}
@@ -0,0 +1,231 @@
+//go:build ignore
+
+// This program generates testdata/airlift/sketches.csv.gz, which contains
+// Airlift HyperLogLogs of the strings of the numbers from 0 up to a count.  It
+// is invoked by go generate.
+//
+// It is a port of the SPARSE_V2 and DENSE_V2 serializations of
+// io.airlift.stats.cardinality.HyperLogLog.  Strings are hashed with the first
+// 64 bits of MurmurHash3 x64 128 with a seed of 0, and a HyperLogLog is sparse
+// as long as it has no more entries than an eighth of its buckets.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+const (
+	sparseV2 = 2
+	denseV2  = 3
+
+	// each sparse entry is a 26 bit bucket index followed by a 6 bit value.
+	valueBits          = 6
+	extendedPrefixBits = 32 - valueBits
+
+	maxDelta = 1<<4 - 1
+)
+
+func main() {
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	fmt.Fprintln(w, "index_bit_length,count,sketch")
+
+	for _, indexBitLength := range []int{4, 8, 11, 12, 16} {
+		for _, count := range []int{0, 1, 2, 10, 100, 200, 1000, 10000, 100000} {
+			hashes := make([]uint64, count)
+			for i := range hashes {
+				hashes[i], _ = murmur3Sum128([]byte(strconv.Itoa(i)), 0)
+			}
+
+			var data []byte
+			if entries := sparseEntries(hashes); len(entries) <= 1<<uint(indexBitLength)/8 {
+				data = []byte{sparseV2, byte(indexBitLength)}
+				data = appendUint16(data, uint16(len(entries)))
+				for _, entry := range entries {
+					data = appendUint32(data, entry)
+				}
+			} else {
+				data = dense(indexBitLength, hashes)
+			}
+
+			fmt.Fprintf(w, "%d,%d,\\x%s\n", indexBitLength, count, hex.EncodeToString(data))
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("testdata/airlift/sketches.csv.gz", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// sparseEntries returns the sorted sparse entries, keeping the largest value
+// for each bucket.
+func sparseEntries(hashes []uint64) []uint32 {
+
+	buckets := make(map[uint32]uint32)
+	for _, hash := range hashes {
+		bucket := uint32(hash >> (64 - extendedPrefixBits))
+		value := uint32(bits.LeadingZeros64(hash<<extendedPrefixBits | 1<<(extendedPrefixBits-1)))
+		if old, ok := buckets[bucket]; !ok || value > old {
+			buckets[bucket] = value
+		}
+	}
+
+	entries := make([]uint32, 0, len(buckets))
+	for bucket, value := range buckets {
+		entries = append(entries, bucket<<valueBits|value)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i] < entries[j] })
+
+	return entries
+}
+
+// dense returns the DENSE_V2 serialization, which holds each bucket as a 4 bit
+// delta from the smallest value, with the even buckets in the high nibbles,
+// followed by the buckets and amounts of the deltas that don't fit.
+func dense(indexBitLength int, hashes []uint64) []byte {
+
+	numberOfBuckets := 1 << uint(indexBitLength)
+
+	registers := make([]int, numberOfBuckets)
+	for _, hash := range hashes {
+		bucket := hash >> uint(64-indexBitLength)
+		value := bits.LeadingZeros64(hash<<uint(indexBitLength)|1<<uint(indexBitLength-1)) + 1
+		if value > registers[bucket] {
+			registers[bucket] = value
+		}
+	}
+
+	baseline := registers[0]
+	for _, value := range registers {
+		if value < baseline {
+			baseline = value
+		}
+	}
+
+	deltas := make([]byte, numberOfBuckets/2)
+	var overflowBuckets []uint16
+	var overflowValues []byte
+	for bucket, value := range registers {
+		delta := value - baseline
+		if delta > maxDelta {
+			overflowBuckets = append(overflowBuckets, uint16(bucket))
+			overflowValues = append(overflowValues, byte(delta-maxDelta))
+			delta = maxDelta
+		}
+		deltas[bucket/2] |= byte(delta << (4 * uint(1-bucket%2)))
+	}
+
+	data := append([]byte{denseV2, byte(indexBitLength), byte(baseline)}, deltas...)
+	data = appendUint16(data, uint16(len(overflowBuckets)))
+	for _, bucket := range overflowBuckets {
+		data = appendUint16(data, bucket)
+	}
+
+	return append(data, overflowValues...)
+}
+
+func appendUint16(data []byte, v uint16) []byte {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+func appendUint32(data []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+// murmur3Sum128 is the x64 128 bit variant of MurmurHash3.
+func murmur3Sum128(data []byte, seed uint64) (uint64, uint64) {
+
+	const c1 = 0x87c37b91114253d5
+	const c2 = 0x4cf5ad432745937f
+
+	h1, h2 := seed, seed
+	length := uint64(len(data))
+
+	for ; len(data) >= 16; data = data[16:] {
+		k1 := binary.LittleEndian.Uint64(data)
+		k2 := binary.LittleEndian.Uint64(data[8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	for i := len(data) - 1; i >= 8; i-- {
+		k2 ^= uint64(data[i]) << (8 * uint(i-8))
+	}
+	if len(data) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	low := len(data)
+	if low > 8 {
+		low = 8
+	}
+	for i := low - 1; i >= 0; i-- {
+		k1 ^= uint64(data[i]) << (8 * uint(i))
+	}
+	if len(data) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= length
+	h2 ^= length
+
+	h1 += h2
+	h2 += h1
+
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+
+	h1 += h2
+	h2 += h1
+
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
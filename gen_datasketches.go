@@ -0,0 +1,472 @@
+//go:build ignore
+
+// This program generates testdata/datasketches/sketches.csv.gz, which contains
+// Apache DataSketches HLL sketches of each type in their compact and updatable
+// forms.  It is invoked by go generate.
+//
+// It is a port of the update path of HllSketch from DataSketches 3.x, which
+// moves from a list of coupons to a hash set of coupons and then to HLL mode,
+// and of the serialization of each mode.  The HIP estimate isn't computed, so
+// it is zero in sketches in HLL mode.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	seed = 9001
+
+	keyBits26 = 26
+	keyMask26 = 1<<keyBits26 - 1
+
+	// modes.
+	modeList = 0
+	modeSet  = 1
+	modeHll  = 2
+
+	// types.
+	typeHll4 = 0
+	typeHll6 = 1
+	typeHll8 = 2
+
+	// flags.
+	flagEmpty      = 4
+	flagCompact    = 8
+	flagOutOfOrder = 16
+)
+
+// lgAuxArrInts is the initial size of the exceptions table of an HLL_4 sketch
+// for each lgConfigK.
+var lgAuxArrInts = []int{0, 2, 2, 2, 2, 2, 2, 3, 3, 3, 4, 4, 5, 5, 6, 7, 8, 9, 10, 11, 12, 13}
+
+func main() {
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	fmt.Fprintln(w, "lg_k,type,compact,count,sketch")
+
+	for _, lgK := range []int{4, 8, 12} {
+		for typ, name := range []string{"HLL_4", "HLL_6", "HLL_8"} {
+			for _, compact := range []bool{true, false} {
+				for _, n := range []int{0, 1, 7, 8, 24, 25, 100, 1000, 100000} {
+					if n == 100000 && lgK == 8 {
+						continue
+					}
+					s := newSketch(lgK, typ)
+					for i := 0; i < n; i++ {
+						s.update([]byte(strconv.Itoa(i)))
+					}
+					fmt.Fprintf(w, "%d,%s,%t,%d,\\x%s\n", lgK, name, compact, n, hex.EncodeToString(s.serialize(compact)))
+				}
+			}
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("testdata/datasketches/sketches.csv.gz", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type sketch struct {
+	lgK, typ int
+	mode     int
+
+	// the coupons of list and set mode.
+	lgArr int
+	arr   []uint32
+	count int
+
+	// the registers of HLL mode.
+	registers []int
+}
+
+func newSketch(lgK, typ int) *sketch {
+	return &sketch{lgK: lgK, typ: typ, mode: modeList, lgArr: 3, arr: make([]uint32, 8)}
+}
+
+// coupon returns the coupon of the data, which holds the value in its upper 6
+// bits and the low 26 bits of the hash, of which the low lgConfigK bits are the
+// slot.
+func coupon(data []byte) uint32 {
+	h1, h2 := murmur3Sum128(data, seed)
+	value := bits.LeadingZeros64(h2)
+	if value > 62 {
+		value = 62
+	}
+	return uint32(value+1)<<keyBits26 | uint32(h1&keyMask26)
+}
+
+func (s *sketch) update(data []byte) {
+
+	if len(data) == 0 {
+		return
+	}
+
+	c := coupon(data)
+	switch s.mode {
+	case modeList:
+		s.listUpdate(c)
+	case modeSet:
+		s.setUpdate(c)
+	default:
+		s.hllUpdate(c)
+	}
+}
+
+func (s *sketch) listUpdate(c uint32) {
+
+	for i := range s.arr {
+		if s.arr[i] == 0 {
+			s.arr[i] = c
+			s.count++
+			if s.count >= len(s.arr) {
+				if s.lgK < 8 {
+					s.promoteHll()
+				} else {
+					s.promoteSet()
+				}
+			}
+			return
+		}
+		if s.arr[i] == c {
+			return
+		}
+	}
+}
+
+func (s *sketch) promoteSet() {
+	old := s.arr
+	s.mode, s.lgArr, s.arr, s.count = modeSet, 5, make([]uint32, 32), 0
+	for _, c := range old {
+		if c != 0 {
+			s.setUpdate(c)
+		}
+	}
+}
+
+func (s *sketch) setUpdate(c uint32) {
+
+	i := setFind(s.arr, s.lgArr, c)
+	if i >= 0 {
+		return
+	}
+
+	s.arr[^i] = c
+	s.count++
+
+	if 4*s.count > 3<<uint(s.lgArr) {
+		if s.lgArr == s.lgK-3 {
+			s.promoteHll()
+			return
+		}
+		old := s.arr
+		s.lgArr++
+		s.arr = make([]uint32, 1<<uint(s.lgArr))
+		for _, c := range old {
+			if c != 0 {
+				s.arr[^setFind(s.arr, s.lgArr, c)] = c
+			}
+		}
+	}
+}
+
+func (s *sketch) promoteHll() {
+	old := s.arr
+	s.mode, s.arr = modeHll, nil
+	s.registers = make([]int, 1<<uint(s.lgK))
+	for _, c := range old {
+		if c != 0 {
+			s.hllUpdate(c)
+		}
+	}
+}
+
+func (s *sketch) hllUpdate(c uint32) {
+	slot := int(c) & (1<<uint(s.lgK) - 1)
+	if value := int(c >> keyBits26); value > s.registers[slot] {
+		s.registers[slot] = value
+	}
+}
+
+// setFind returns the index of the coupon in the hash set, or the complement
+// of the index of the empty slot where it belongs.
+func setFind(arr []uint32, lgArr int, c uint32) int {
+
+	mask := uint32(1)<<uint(lgArr) - 1
+	probe := c & mask
+	loop := probe
+
+	for {
+		if arr[probe] == 0 {
+			return ^int(probe)
+		}
+		if arr[probe] == c {
+			return int(probe)
+		}
+		stride := (c&keyMask26)>>uint(lgArr) | 1
+		probe = (probe + stride) & mask
+		if probe == loop {
+			log.Fatal("hash set is full")
+		}
+	}
+}
+
+// auxFind is setFind for the exceptions table of an HLL_4 sketch, which is
+// keyed by slot.
+func auxFind(arr []uint32, lgArr, lgK int, slot uint32) int {
+
+	mask := uint32(1)<<uint(lgArr) - 1
+	probe := slot & mask
+	loop := probe
+
+	for {
+		if arr[probe] == 0 {
+			return ^int(probe)
+		}
+		if slot == arr[probe]&(1<<uint(lgK)-1) {
+			return int(probe)
+		}
+		stride := slot>>uint(lgArr) | 1
+		probe = (probe + stride) & mask
+		if probe == loop {
+			log.Fatal("exceptions table is full")
+		}
+	}
+}
+
+// auxLgArr returns the size of an exceptions table holding count entries.
+func auxLgArr(count, lgK int) int {
+	p := 1
+	for p < count {
+		p <<= 1
+	}
+	if 4*count > 3*p {
+		p <<= 1
+	}
+	lg := bits.Len(uint(p)) - 1
+	if lg < lgAuxArrInts[lgK] {
+		return lgAuxArrInts[lgK]
+	}
+	return lg
+}
+
+func (s *sketch) serialize(compact bool) []byte {
+
+	flags := byte(0)
+	if compact {
+		flags = flagCompact
+	}
+
+	lgK, typ := byte(s.lgK), byte(s.typ)
+
+	switch s.mode {
+	case modeList:
+		if s.count == 0 {
+			return []byte{2, 1, 7, lgK, 3, flags | flagEmpty, 0, typ << 2}
+		}
+		data := []byte{2, 1, 7, lgK, 3, flags, byte(s.count), typ << 2}
+		return appendCoupons(data, s.arr, compact)
+	case modeSet:
+		data := []byte{3, 1, 7, lgK, byte(s.lgArr), flags | flagOutOfOrder, 0, 1 | typ<<2}
+		data = appendUint32(data, uint32(s.count))
+		return appendCoupons(data, s.arr, compact)
+	}
+
+	k := 1 << uint(s.lgK)
+
+	var kxq0, kxq1 float64
+	for _, value := range s.registers {
+		if value < 32 {
+			kxq0 += math.Ldexp(1, -value)
+		} else {
+			kxq1 += math.Ldexp(1, -value)
+		}
+	}
+
+	var curMin, numAtCurMin, lgArr int
+	var registers []byte
+	var aux, auxInts []uint32
+
+	switch s.typ {
+	case typeHll4:
+		curMin = s.registers[0]
+		for _, value := range s.registers {
+			if value < curMin {
+				curMin = value
+			}
+		}
+		registers = make([]byte, k/2)
+		for slot, value := range s.registers {
+			if value == curMin {
+				numAtCurMin++
+			}
+			nibble := value - curMin
+			if nibble >= 15 {
+				nibble = 15
+				aux = append(aux, uint32(value)<<keyBits26|uint32(slot))
+			}
+			registers[slot>>1] |= byte(nibble << (4 * uint(slot&1)))
+		}
+		lgArr = lgAuxArrInts[s.lgK]
+		if len(aux) > 0 {
+			lgArr = auxLgArr(len(aux), s.lgK)
+		}
+		if compact {
+			auxInts = aux
+		} else {
+			auxInts = make([]uint32, 1<<uint(lgArr))
+			for _, a := range aux {
+				auxInts[^auxFind(auxInts, lgArr, s.lgK, a&uint32(k-1))] = a
+			}
+		}
+	case typeHll6:
+		registers = make([]byte, k*3/4+1)
+		for slot, value := range s.registers {
+			if value == 0 {
+				numAtCurMin++
+			}
+			pos := slot * 6
+			b, shift := pos/8, uint(pos%8)
+			registers[b] |= byte(value << shift)
+			registers[b+1] |= byte(value >> (8 - shift))
+		}
+	default:
+		registers = make([]byte, k)
+		for slot, value := range s.registers {
+			if value == 0 {
+				numAtCurMin++
+			}
+			registers[slot] = byte(value)
+		}
+	}
+
+	data := []byte{10, 1, 7, lgK, byte(lgArr), flags, byte(curMin), 2 | typ<<2}
+	data = appendUint64(data, math.Float64bits(0))
+	data = appendUint64(data, math.Float64bits(kxq0))
+	data = appendUint64(data, math.Float64bits(kxq1))
+	data = appendUint32(data, uint32(numAtCurMin))
+	data = appendUint32(data, uint32(len(aux)))
+	data = append(data, registers...)
+	for _, a := range auxInts {
+		data = appendUint32(data, a)
+	}
+
+	return data
+}
+
+// appendCoupons appends the coupons of list or set mode, leaving out the empty
+// entries in the compact form.
+func appendCoupons(data []byte, arr []uint32, compact bool) []byte {
+	for _, c := range arr {
+		if c != 0 || !compact {
+			data = appendUint32(data, c)
+		}
+	}
+	return data
+}
+
+func appendUint32(data []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+func appendUint64(data []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(data, buf[:]...)
+}
+
+// murmur3Sum128 is the x64 128 bit variant of MurmurHash3.
+func murmur3Sum128(data []byte, seed uint64) (uint64, uint64) {
+
+	const c1 = 0x87c37b91114253d5
+	const c2 = 0x4cf5ad432745937f
+
+	h1, h2 := seed, seed
+	length := uint64(len(data))
+
+	for ; len(data) >= 16; data = data[16:] {
+		k1 := binary.LittleEndian.Uint64(data)
+		k2 := binary.LittleEndian.Uint64(data[8:])
+
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	var k1, k2 uint64
+	for i := len(data) - 1; i >= 8; i-- {
+		k2 ^= uint64(data[i]) << (8 * uint(i-8))
+	}
+	if len(data) > 8 {
+		k2 *= c2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= c1
+		h2 ^= k2
+	}
+	low := len(data)
+	if low > 8 {
+		low = 8
+	}
+	for i := low - 1; i >= 0; i-- {
+		k1 ^= uint64(data[i]) << (8 * uint(i))
+	}
+	if len(data) > 0 {
+		k1 *= c1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= c2
+		h1 ^= k1
+	}
+
+	h1 ^= length
+	h2 ^= length
+
+	h1 += h2
+	h2 += h1
+
+	h1 = fmix64(h1)
+	h2 = fmix64(h2)
+
+	h1 += h2
+	h2 += h1
+
+	return h1, h2
+}
+
+func fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,377 @@
+//go:build ignore
+
+// This program generates testdata/redis/pfadd.csv.gz, which contains the
+// values of Redis HyperLogLog keys after a single PFADD to a new key.  It is
+// invoked by go generate.
+//
+// It is a port of PFADD from src/hyperloglog.c in Redis 7.0.  A new key starts
+// as a single XZERO opcode, hllSparseSet splits the opcode that covers a
+// register and then merges adjacent VAL opcodes with the same value, and the
+// key is promoted to the dense encoding when a value doesn't fit in a VAL
+// opcode or the sparse encoding would grow beyond hll-sparse-max-bytes.  The
+// resulting opcodes depend on the order in which the registers were set.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+const (
+	hllP         = 14
+	hllQ         = 64 - hllP
+	hllRegisters = 1 << hllP
+	hllBits      = 6
+	hllDenseSize = hllRegisters * hllBits / 8
+
+	hllHeaderSize     = 16
+	hllSparseMaxBytes = 3000 // the default hll-sparse-max-bytes.
+
+	hllDense  = 0
+	hllSparse = 1
+
+	hllSparseZeroMaxLen = 64
+	hllSparseValMaxLen  = 4
+	hllSparseValMax     = 32
+)
+
+func main() {
+
+	cases := [][]string{
+		{},
+		{"a"},
+		{"a", "b", "c"},
+		{"foo", "bar", "zap", "a"},
+		{"hello", "world"},
+		{"12345678", "123456789", "1234567"},
+	}
+	for _, n := range []int{10, 100, 1000, 2000, 20000} {
+		elements := make([]string, n)
+		for i := range elements {
+			elements[i] = fmt.Sprintf("element:%d", i)
+		}
+		cases = append(cases, elements)
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	fmt.Fprintln(w, "elements,value")
+
+	for _, elements := range cases {
+		k := newKey()
+		for _, element := range elements {
+			k.add([]byte(element))
+		}
+		fmt.Fprintf(w, "%s,\\x%s\n", strings.Join(elements, " "), hex.EncodeToString(k.value()))
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("testdata/redis/pfadd.csv.gz", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// key is the value of a HyperLogLog key.  dense is nil while the key uses the
+// sparse encoding.
+type key struct {
+	sparse  []byte
+	dense   []byte
+	invalid bool
+}
+
+func newKey() *key {
+	return &key{sparse: xzeroOp(hllRegisters)}
+}
+
+func (k *key) value() []byte {
+
+	value := []byte{'H', 'Y', 'L', 'L', hllSparse, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if k.invalid {
+		value[15] = 0x80
+	}
+
+	if k.dense != nil {
+		value[4] = hllDense
+		return append(value, k.dense...)
+	}
+	return append(value, k.sparse...)
+}
+
+// add is hllAdd.
+func (k *key) add(element []byte) {
+
+	index, count := hllPatLen(element)
+
+	if k.dense == nil {
+		k.sparseSet(index, count)
+		return
+	}
+
+	if denseGet(k.dense, index) < count {
+		denseSet(k.dense, index, count)
+		k.invalid = true
+	}
+}
+
+// hllPatLen returns the register index and the value for an element.
+func hllPatLen(element []byte) (int, int) {
+
+	hash := murmurHash64A(element, 0xadc83b19)
+	index := int(hash & (hllRegisters - 1))
+
+	hash >>= hllP
+	hash |= 1 << hllQ
+
+	bit, count := uint64(1), 1
+	for hash&bit == 0 {
+		count++
+		bit <<= 1
+	}
+
+	return index, count
+}
+
+// sparseSet is hllSparseSet.
+func (k *key) sparseSet(index, count int) {
+
+	if count > hllSparseValMax {
+		k.promote(index, count)
+		return
+	}
+
+	s := k.sparse
+
+	// find the opcode that covers the register.
+	p, first, prev, span := 0, 0, -1, 0
+	for p < len(s) {
+		oplen := 1
+		switch {
+		case isZero(s[p]):
+			span = zeroLen(s[p])
+		case isVal(s[p]):
+			span = valLen(s[p])
+		default:
+			span = xzeroLen(s[p], s[p+1])
+			oplen = 2
+		}
+		if index <= first+span-1 {
+			break
+		}
+		prev = p
+		p += oplen
+		first += span
+	}
+
+	if isVal(s[p]) {
+		if valValue(s[p]) >= count {
+			return
+		}
+		if span == 1 {
+			s[p] = valOp(count, 1)
+			k.merge(prev)
+			return
+		}
+	}
+
+	if isZero(s[p]) && span == 1 {
+		s[p] = valOp(count, 1)
+		k.merge(prev)
+		return
+	}
+
+	// split the opcode into up to three opcodes.
+	last := first + span - 1
+
+	var seq []byte
+	if isVal(s[p]) {
+		value := valValue(s[p])
+		if index != first {
+			seq = append(seq, valOp(value, index-first))
+		}
+		seq = append(seq, valOp(count, 1))
+		if index != last {
+			seq = append(seq, valOp(value, last-index))
+		}
+	} else {
+		if index != first {
+			seq = append(seq, zeroOps(index-first)...)
+		}
+		seq = append(seq, valOp(count, 1))
+		if index != last {
+			seq = append(seq, zeroOps(last-index)...)
+		}
+	}
+
+	oldlen := 1
+	if isXZero(s[p]) {
+		oldlen = 2
+	}
+
+	if delta := len(seq) - oldlen; delta > 0 && hllHeaderSize+len(s)+delta > hllSparseMaxBytes {
+		k.promote(index, count)
+		return
+	}
+
+	k.sparse = append(append(append([]byte(nil), s[:p]...), seq...), s[p+oldlen:]...)
+	k.merge(prev)
+}
+
+// merge is the updated label of hllSparseSet, which merges adjacent VAL opcodes
+// with the same value, starting at the opcode before the one that was changed.
+func (k *key) merge(prev int) {
+
+	s := k.sparse
+
+	p := prev
+	if p < 0 {
+		p = 0
+	}
+
+	for scanlen := 5; p < len(s) && scanlen > 0; scanlen-- {
+		if isXZero(s[p]) {
+			p += 2
+			continue
+		}
+		if isZero(s[p]) {
+			p++
+			continue
+		}
+		if p+1 < len(s) && isVal(s[p+1]) {
+			value := valValue(s[p])
+			if length := valLen(s[p]) + valLen(s[p+1]); value == valValue(s[p+1]) && length <= hllSparseValMaxLen {
+				s[p+1] = valOp(value, length)
+				s = append(s[:p], s[p+1:]...)
+				continue
+			}
+		}
+		p++
+	}
+
+	k.sparse = s
+	k.invalid = true
+}
+
+// promote is hllSparseToDense followed by setting the register.
+func (k *key) promote(index, count int) {
+
+	k.dense = make([]byte, hllDenseSize)
+
+	regnum := 0
+	for p := 0; p < len(k.sparse); {
+		switch b := k.sparse[p]; {
+		case isZero(b):
+			regnum += zeroLen(b)
+			p++
+		case isXZero(b):
+			regnum += xzeroLen(b, k.sparse[p+1])
+			p += 2
+		default:
+			for i := 0; i < valLen(b); i++ {
+				denseSet(k.dense, regnum, valValue(b))
+				regnum++
+			}
+			p++
+		}
+	}
+	if regnum != hllRegisters {
+		log.Fatalf("sparse encoding has %d registers", regnum)
+	}
+
+	k.sparse = nil
+
+	if denseGet(k.dense, index) < count {
+		denseSet(k.dense, index, count)
+	}
+	k.invalid = true
+}
+
+func isZero(b byte) bool  { return b&0xc0 == 0 }
+func isXZero(b byte) bool { return b&0xc0 == 0x40 }
+func isVal(b byte) bool   { return b&0x80 != 0 }
+
+func zeroLen(b byte) int     { return int(b&0x3f) + 1 }
+func xzeroLen(a, b byte) int { return (int(a&0x3f)<<8 | int(b)) + 1 }
+func valValue(b byte) int    { return int(b>>2&0x1f) + 1 }
+func valLen(b byte) int      { return int(b&0x3) + 1 }
+
+func valOp(value, length int) byte {
+	return byte(0x80 | (value-1)<<2 | (length - 1))
+}
+
+func xzeroOp(length int) []byte {
+	length--
+	return []byte{byte(0x40 | length>>8), byte(length)}
+}
+
+// zeroOps returns a ZERO opcode for short runs and an XZERO opcode otherwise.
+func zeroOps(length int) []byte {
+	if length > hllSparseZeroMaxLen {
+		return xzeroOp(length)
+	}
+	return []byte{byte(length - 1)}
+}
+
+// denseGet is HLL_DENSE_GET_REGISTER.  The last register doesn't reach into a
+// following byte.
+func denseGet(registers []byte, regnum int) int {
+	b := regnum * hllBits / 8
+	fb := uint(regnum * hllBits & 7)
+	value := int(registers[b]) >> fb
+	if b+1 < len(registers) {
+		value |= int(registers[b+1]) << (8 - fb)
+	}
+	return value & (1<<hllBits - 1)
+}
+
+// denseSet is HLL_DENSE_SET_REGISTER.
+func denseSet(registers []byte, regnum, value int) {
+	b := regnum * hllBits / 8
+	fb := uint(regnum * hllBits & 7)
+	registers[b] &^= byte((1<<hllBits - 1) << fb)
+	registers[b] |= byte(value << fb)
+	if b+1 < len(registers) {
+		registers[b+1] &^= byte((1<<hllBits - 1) >> (8 - fb))
+		registers[b+1] |= byte(value >> (8 - fb))
+	}
+}
+
+// murmurHash64A is MurmurHash64A as used by Redis.
+func murmurHash64A(data []byte, seed uint64) uint64 {
+
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ uint64(len(data))*m
+
+	for ; len(data) >= 8; data = data[8:] {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+	}
+
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+
+	return h
+}
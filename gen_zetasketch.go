@@ -0,0 +1,183 @@
+//go:build ignore
+
+// This program generates testdata/zetasketch/sketches.csv.gz, which contains
+// ZetaSketch HLL++ sketches of the first hashes from SplitMix64.  It is invoked
+// by go generate.
+//
+// It is a port of the sparse and normal encodings in
+// com.google.zetasketch.internal.hllplus.  The sparse precision is the normal
+// precision plus 5, capped at 25 like the default of HyperLogLogPlusPlus, and a
+// sketch stays sparse as long as its sparse data is no larger than three
+// quarters of the normal data.
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// AggregatorStateProto fields.
+	typeField       = 1
+	numValuesField  = 2
+	encodingField   = 3
+	hyperLogLogPlus = 112 // HYPERLOGLOG_PLUS_UNIQUE and the extension field.
+
+	// HyperLogLogPlusUniqueStateProto fields.
+	sparseSizeField      = 2
+	precisionField       = 3
+	sparsePrecisionField = 4
+	dataField            = 5
+	sparseDataField      = 6
+
+	rhoWBits = 6
+)
+
+func main() {
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	fmt.Fprintln(w, "precision,count,sketch")
+
+	for _, precision := range []int{10, 12, 15} {
+		for _, count := range []int{0, 1, 2, 10, 100, 300, 2000, 5000, 100000} {
+			fmt.Fprintf(w, "%d,%d,\\x%s\n", precision, count, hex.EncodeToString(sketch(precision, count)))
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile("testdata/zetasketch/sketches.csv.gz", buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// splitMix64 returns the i-th output of SplitMix64 with a seed of 0.
+func splitMix64(i int) uint64 {
+	z := uint64(i+1) * 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// sketch returns the serialized AggregatorStateProto after adding count hashes.
+func sketch(precision, count int) []byte {
+
+	sparsePrecision := precision + 5
+	if sparsePrecision > 25 {
+		sparsePrecision = 25
+	}
+
+	hashes := make([]uint64, count)
+	for i := range hashes {
+		hashes[i] = splitMix64(i)
+	}
+
+	sparseSize, sparseData := sparse(precision, sparsePrecision, hashes)
+
+	var state []byte
+	if count > 0 && len(sparseData) <= 3<<uint(precision)/4 {
+		state = appendVarintField(state, sparseSizeField, uint64(sparseSize))
+		state = appendVarintField(state, precisionField, uint64(precision))
+		state = appendVarintField(state, sparsePrecisionField, uint64(sparsePrecision))
+		state = appendBytesField(state, sparseDataField, sparseData)
+	} else {
+		state = appendVarintField(state, precisionField, uint64(precision))
+		state = appendVarintField(state, sparsePrecisionField, uint64(sparsePrecision))
+		if count > 0 {
+			state = appendBytesField(state, dataField, normal(precision, hashes))
+		}
+	}
+
+	var aggregator []byte
+	aggregator = appendVarintField(aggregator, typeField, hyperLogLogPlus)
+	aggregator = appendVarintField(aggregator, numValuesField, uint64(count))
+	aggregator = appendVarintField(aggregator, encodingField, 2)
+	return appendBytesField(aggregator, hyperLogLogPlus, state)
+}
+
+// normal returns the registers of the normal encoding, which are indexed by
+// the highest bits of the hash.
+func normal(precision int, hashes []uint64) []byte {
+
+	registers := make([]byte, 1<<uint(precision))
+	for _, hash := range hashes {
+		index := hash >> uint(64-precision)
+		rho := byte(bits.LeadingZeros64(hash<<uint(precision)|1<<uint(precision-1)) + 1)
+		if rho > registers[index] {
+			registers[index] = rho
+		}
+	}
+
+	return registers
+}
+
+// sparse returns the number of values and the difference encoded varints of
+// the sparse encoding.  A value is the sparse index when its bits below the
+// normal precision aren't all zero, and otherwise it is a flag followed by the
+// normal index and the number of leading zeros after the sparse index.  Only
+// the largest value is kept for a sparse index or a flag and normal index.
+func sparse(precision, sparsePrecision int, hashes []uint64) (int, []byte) {
+
+	flagBit := sparsePrecision
+	if precision+rhoWBits > flagBit {
+		flagBit = precision + rhoWBits
+	}
+	flag := uint64(1) << uint(flagBit)
+
+	values := make(map[uint64]uint64)
+	for _, hash := range hashes {
+		sparseIndex := hash >> uint(64-sparsePrecision)
+
+		value, key := sparseIndex, sparseIndex
+		if sparseIndex&(1<<uint(sparsePrecision-precision)-1) == 0 {
+			rhoW := uint64(bits.LeadingZeros64(hash<<uint(sparsePrecision)|1<<uint(sparsePrecision-1)) + 1)
+			value = flag | sparseIndex>>uint(sparsePrecision-precision)<<rhoWBits | rhoW
+			key = value &^ (1<<rhoWBits - 1)
+		}
+
+		if value > values[key] {
+			values[key] = value
+		}
+	}
+
+	sorted := make([]uint64, 0, len(values))
+	for _, value := range values {
+		sorted = append(sorted, value)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var data []byte
+	previous := uint64(0)
+	for _, value := range sorted {
+		data = appendVarint(data, value-previous)
+		previous = value
+	}
+
+	return len(sorted), data
+}
+
+func appendVarint(data []byte, v uint64) []byte {
+	for v >= 0x80 {
+		data = append(data, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(data, byte(v))
+}
+
+func appendVarintField(data []byte, number int, v uint64) []byte {
+	return appendVarint(appendVarint(data, uint64(number)<<3), v)
+}
+
+func appendBytesField(data []byte, number int, b []byte) []byte {
+	data = appendVarint(data, uint64(number)<<3|2)
+	return append(appendVarint(data, uint64(len(b))), b...)
+}
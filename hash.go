@@ -0,0 +1,80 @@
+package hll
+
+import (
+	"encoding/binary"
+)
+
+// The functions in this file produce the same hash values as the hll_hash_*
+// family of functions in https://github.com/aggregateknowledge/postgresql-hll,
+// which hash the in-memory representation of the value with MurmurHash3
+// x64_128 and keep the first 64 bits.  Each function accepts an optional seed that
+// corresponds to the optional seed argument of the PostgreSQL function.  Only
+// the first seed is used.  As with PostgreSQL, negative seeds are permitted but
+// are not compatible with other implementations of the storage spec.
+//
+// The returned values are intended to be passed to Hll.AddRaw.  PostgreSQL
+// returns them as a signed bigint, so they must be converted with int64(...) if
+// they are to be compared with the output of a query.
+
+// HashBigint returns the hash of the provided value as computed by
+// hll_hash_bigint.  With the default seed, the result is identical to the
+// value added by Hll.AddInt64.
+func HashBigint(value int64, seed ...int32) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(value))
+	return pgHash(buf[:], seed)
+}
+
+// HashInteger returns the hash of the provided value as computed by
+// hll_hash_integer.
+func HashInteger(value int32, seed ...int32) uint64 {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(value))
+	return pgHash(buf[:], seed)
+}
+
+// HashSmallint returns the hash of the provided value as computed by
+// hll_hash_smallint.
+func HashSmallint(value int16, seed ...int32) uint64 {
+	var buf [2]byte
+	binary.LittleEndian.PutUint16(buf[:], uint16(value))
+	return pgHash(buf[:], seed)
+}
+
+// HashBoolean returns the hash of the provided value as computed by
+// hll_hash_boolean.
+func HashBoolean(value bool, seed ...int32) uint64 {
+	var buf [1]byte
+	if value {
+		buf[0] = 1
+	}
+	return pgHash(buf[:], seed)
+}
+
+// HashText returns the hash of the provided value as computed by hll_hash_text.
+// The string is hashed as its UTF-8 bytes, which matches a database using the
+// UTF8 server encoding.
+func HashText(value string, seed ...int32) uint64 {
+	return pgHash([]byte(value), seed)
+}
+
+// HashBytea returns the hash of the provided value as computed by
+// hll_hash_bytea.
+func HashBytea(value []byte, seed ...int32) uint64 {
+	return pgHash(value, seed)
+}
+
+// pgHash hashes the provided bytes in the manner of the postgresql-hll
+// extension.
+func pgHash(data []byte, seed []int32) uint64 {
+
+	var s uint32
+	if len(seed) > 0 {
+		// NOTE : the extension passes the int32 seed straight through to the
+		//        uint32 parameter of MurmurHash3_x64_128.
+		s = uint32(seed[0])
+	}
+
+	h1, _ := murmur3Sum128(data, s)
+	return h1
+}
@@ -0,0 +1,88 @@
+package hll
+
+import (
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Test_PostgresHashes runs through the hash vectors in
+// integration_tests/hash/postgresql_hll_hash.csv.gz.  Each line contains the
+// name of the PostgreSQL function, the input formatted as a PostgreSQL literal,
+// the seed, and the resulting hash as the signed bigint printed by psql.
+// integration_tests/hash/postgresql_hll_hash.sql is the psql script that
+// writes them with the extension's hll_hash_* functions.
+func Test_PostgresHashes(t *testing.T) {
+
+	readCSV(t, "integration_tests/hash/postgresql_hll_hash.csv.gz", func(parts []string, lineNo int) {
+
+		require.Equal(t, 4, len(parts), "required 4 columns at line %d", lineNo)
+
+		function, input := parts[0], parts[1]
+
+		seed, err := strconv.ParseInt(parts[2], 10, 32)
+		require.NoError(t, err, "invalid seed at line %d", lineNo)
+
+		expected, err := strconv.ParseInt(parts[3], 10, 64)
+		require.NoError(t, err, "invalid hash at line %d", lineNo)
+
+		var actual uint64
+
+		switch function {
+		case "hll_hash_bigint":
+			value, err := strconv.ParseInt(input, 10, 64)
+			require.NoError(t, err, "invalid bigint at line %d", lineNo)
+			actual = HashBigint(value, int32(seed))
+		case "hll_hash_integer":
+			value, err := strconv.ParseInt(input, 10, 32)
+			require.NoError(t, err, "invalid integer at line %d", lineNo)
+			actual = HashInteger(int32(value), int32(seed))
+		case "hll_hash_smallint":
+			value, err := strconv.ParseInt(input, 10, 16)
+			require.NoError(t, err, "invalid smallint at line %d", lineNo)
+			actual = HashSmallint(int16(value), int32(seed))
+		case "hll_hash_boolean":
+			value, err := strconv.ParseBool(input)
+			require.NoError(t, err, "invalid boolean at line %d", lineNo)
+			actual = HashBoolean(value, int32(seed))
+		case "hll_hash_text":
+			actual = HashText(input, int32(seed))
+		case "hll_hash_bytea":
+			require.True(t, strings.HasPrefix(input, "\\x"), "missing \\x at line %d", lineNo)
+			value, err := hex.DecodeString(input[2:])
+			require.NoError(t, err, "invalid hex at line %d", lineNo)
+			actual = HashBytea(value, int32(seed))
+		default:
+			require.Fail(t, "unknown function", "%s at line %d", function, lineNo)
+		}
+
+		require.Equal(t, expected, int64(actual), "incorrect hash at line %d", lineNo)
+	})
+}
+
+func Test_PostgresHashes_DefaultSeed(t *testing.T) {
+
+	// omitting the seed is the same as passing 0.
+	require.Equal(t, HashBigint(42, 0), HashBigint(42))
+	require.Equal(t, HashInteger(42, 0), HashInteger(42))
+	require.Equal(t, HashSmallint(42, 0), HashSmallint(42))
+	require.Equal(t, HashBoolean(true, 0), HashBoolean(true))
+	require.Equal(t, HashText("hello", 0), HashText("hello"))
+	require.Equal(t, HashBytea([]byte("hello"), 0), HashBytea([]byte("hello")))
+
+	// the Hll convenience functions match the bigint and text hashes.
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold}
+
+	h1 := newHll(t, settings)
+	h1.AddInt64(42)
+	h1.AddString("hello")
+
+	h2 := newHll(t, settings)
+	h2.AddRaw(HashBigint(42))
+	h2.AddRaw(HashText("hello"))
+
+	require.Equal(t, h1.ToBytes(), h2.ToBytes())
+}
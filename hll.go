@@ -1,6 +1,7 @@
 package hll
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
@@ -24,9 +25,16 @@ const (
 // byte slice is truncated.
 var ErrInsufficientBytes = errors.New("insufficient bytes to deserialize Hll")
 
-// ErrIncompatible is returned by StrictUnion in cases where the two Hlls have
-// incompatible settings that prevent the operation from occurring.
-var ErrIncompatible = errors.New("cannot StrictUnion Hlls with different regwidth or log2m settings")
+// ErrIncompatible is returned by StrictUnion and the set operations such as
+// IntersectionCardinality in cases where the Hlls have incompatible settings
+// that prevent the operation from occurring.
+var ErrIncompatible = errors.New("cannot combine Hlls with different regwidth, log2m, or hasher settings")
+
+// Hasher computes the 64 bit hash of a value prior to adding it to an Hll.  See
+// the Hasher field of Settings.
+type Hasher interface {
+	Sum64(data []byte) uint64
+}
 
 // Hll is a probabilistic set of hashed elements.  It supports add and union
 // operations in addition to estimating the cardinality.  The zero value is an
@@ -58,33 +66,7 @@ func NewHll(s Settings) (Hll, error) {
 // specify an invalid configuration, or if the byte slice is truncated.
 func FromBytes(bytes []byte) (Hll, error) {
 
-	if len(bytes) < 3 {
-		return Hll{}, ErrInsufficientBytes
-	}
-
-	version, storageType := int(bytes[0]>>4), storageType(bytes[0]&0xf)
-	if version != 1 {
-		return Hll{}, fmt.Errorf("unsupported Hll version: %d", version)
-	}
-
-	// NOTE : this means undefined cannot be instantiated!  this is compatible
-	//        with the Java impl even though the PG impl would allow it.
-	if storageType < empty || storageType > dense {
-		return Hll{}, fmt.Errorf("invalid Hll type: %d", storageType)
-	}
-
-	regwidth, log2m := (bytes[1]>>5)+1, bytes[1]&0x1f
-
-	sparseEnabled, explicitThreshold := unpackCutoffByte(bytes[2])
-
-	settings := Settings{
-		Log2m:             int(log2m),
-		Regwidth:          int(regwidth),
-		SparseEnabled:     sparseEnabled,
-		ExplicitThreshold: explicitThreshold,
-	}
-
-	internalSettings, err := settings.toInternal()
+	storageType, settings, err := parseHeader(bytes)
 
 	// NOTE : in this error case, the Hll is undefined and will not
 	//        auto-initialize to an empty hll if an exported function is called.
@@ -92,7 +74,13 @@ func FromBytes(bytes []byte) (Hll, error) {
 		return Hll{}, err
 	}
 
-	h := Hll{settings: internalSettings}
+	// the length is checked before allocating dense storage so that a header
+	// alone can't force an allocation of up to 2^31 registers.
+	if storageType == dense && len(bytes)-3 != denseSizeInBytes(settings) {
+		return Hll{}, ErrInsufficientBytes
+	}
+
+	h := Hll{settings: settings}
 
 	switch storageType {
 	case explicit:
@@ -116,6 +104,44 @@ func FromBytes(bytes []byte) (Hll, error) {
 	return h, nil
 }
 
+// parseHeader reads the storage type and settings from the 3 header bytes of a
+// serialized Hll.
+func parseHeader(bytes []byte) (storageType, *settings, error) {
+
+	if len(bytes) < 3 {
+		return undefined, nil, ErrInsufficientBytes
+	}
+
+	version, storageType := int(bytes[0]>>4), storageType(bytes[0]&0xf)
+	if version != 1 {
+		return undefined, nil, fmt.Errorf("unsupported Hll version: %d", version)
+	}
+
+	// NOTE : this means undefined cannot be instantiated!  this is compatible
+	//        with the Java impl even though the PG impl would allow it.
+	if storageType < empty || storageType > dense {
+		return undefined, nil, fmt.Errorf("invalid Hll type: %d", storageType)
+	}
+
+	regwidth, log2m := (bytes[1]>>5)+1, bytes[1]&0x1f
+
+	sparseEnabled, explicitThreshold := unpackCutoffByte(bytes[2])
+
+	settings := Settings{
+		Log2m:             int(log2m),
+		Regwidth:          int(regwidth),
+		SparseEnabled:     sparseEnabled,
+		ExplicitThreshold: explicitThreshold,
+	}
+
+	internalSettings, err := settings.toInternal()
+	if err != nil {
+		return undefined, nil, err
+	}
+
+	return storageType, internalSettings, nil
+}
+
 // Settings returns the Settings for this Hll.
 func (h *Hll) Settings() Settings {
 	h.initOrPanic()
@@ -144,45 +170,16 @@ func (h *Hll) AddRaw(value uint64) {
 	// bootstrap case...if this is an empty HLL, it needs storage so we can add
 	// to it.
 	if h.storage == nil {
-		if h.settings.explicitThreshold > 0 {
-			h.storage = make(explicitStorage)
-		} else if h.settings.sparseEnabled {
-			h.storage = make(sparseStorage)
-		} else {
-			h.storage = newDenseStorage(h.settings)
-		}
+		h.bootstrap()
 	}
 
 	switch s := h.storage.(type) {
 	case explicitStorage:
 		s[value] = struct{}{}
 	case registers:
-		// following documentation courtesy of the java implementation:
-		//
-		// p(w): position of the least significant set bit (one-indexed)
-		// By contract: p(w) <= 2^(registerValueInBits) - 1 (the max register
-		// value)
-		//
-		// By construction of pwMaxMask,
-		//      lsb(pwMaxMask) = 2^(registerValueInBits) - 2,
-		// thus lsb(any_long | pwMaxMask) <= 2^(registerValueInBits) - 2,
-		// thus 1 + lsb(any_long | pwMaxMask) <= 2^(registerValueInBits) -1.
-		substreamValue := uint64(value >> uint(h.settings.log2m))
-		if substreamValue == 0 {
-			// The paper does not cover p(0x0), so the special value 0 is used.
-			// 0 is the original initialization value of the registers, so by
-			// doing this the multiset simply ignores it. This is acceptable
-			// because the probability is 1/(2^(2^registerSizeInBits)).
-			return
+		if i, pW := h.settings.register(value); pW > 0 {
+			s.setIfGreater(h.settings, i, pW)
 		}
-
-		// NOTE : trailing zeros == the 0-based index of the least significant 1
-		//        bit.
-		pW := (byte)(1 + bits.TrailingZeros64(substreamValue|h.settings.pwMaxMask))
-		// NOTE:  no +1 as in paper since 0-based indexing
-		i := int(value & h.settings.mBitsMask)
-
-		s.setIfGreater(h.settings, i, pW)
 	}
 
 	if h.storage.overCapacity(h.settings) {
@@ -190,53 +187,200 @@ func (h *Hll) AddRaw(value uint64) {
 	}
 }
 
-// Cardinality estimates the number of values that have been added to this Hll.
-func (h *Hll) Cardinality() uint64 {
+// AddRawBatch adds each of the observed values into the Hll.  The resulting
+// Hll is identical to the one produced by calling AddRaw for every value, but
+// the per-value overhead of initialization, storage type inspection, and
+// capacity checks is amortized across the batch.  As with AddRaw, zero values
+// are ignored.
+func (h *Hll) AddRawBatch(values []uint64) {
 
 	h.initOrPanic()
 
-	switch s := h.storage.(type) {
-	case explicitStorage:
-		return uint64(len(s))
-	case registers:
-		sum, numberOfZeroes /*"V" in the paper*/ := s.indicator(h.settings)
-
-		// apply the estimate and correction to the indicator function
-		estimator := h.settings.alphaMSquared / sum
-
-		if (numberOfZeroes != 0) && (estimator < h.settings.smallEstimatorCutoff) {
-			// following documentation courtesy of the java implementation:
-			// The "small range correction" formula from the HyperLogLog
-			// algorithm. Only appropriate if both the estimator is smaller than
-			// (5/2) * m and there are still registers that have the zero value.
-			m := 1 << uint(h.settings.log2m)
-			smallEstimator := float64(m) * math.Log(float64(m)/float64(numberOfZeroes))
-			return uint64(math.Ceil(smallEstimator))
+	// each pass through the loop adds values to the current storage type until
+	// it either runs out of values or needs to be upgraded, in which case the
+	// remaining values are handled by the next storage type.
+	for len(values) > 0 {
+
+		switch s := h.storage.(type) {
+		case nil:
+			// by contract...ignore zero.  only bootstrap the storage once there
+			// is a value to add so that an all-zero batch leaves the Hll empty.
+			if values[0] == 0 {
+				values = values[1:]
+			} else {
+				h.bootstrap()
+			}
+		case explicitStorage:
+			n := 0
+			for n < len(values) {
+				value := values[n]
+				n++
+				if value == 0 {
+					continue
+				}
+				s[value] = struct{}{}
+				if s.overCapacity(h.settings) {
+					h.upgrade()
+					break
+				}
+			}
+			values = values[n:]
+		case sparseStorage:
+			n := 0
+			for n < len(values) {
+				i, pW := h.settings.register(values[n])
+				n++
+				if pW == 0 {
+					continue
+				}
+				s.setIfGreater(h.settings, i, pW)
+				if s.overCapacity(h.settings) {
+					h.upgrade()
+					break
+				}
+			}
+			values = values[n:]
+		case denseStorage:
+			// dense storage never needs to be upgraded.
+			for _, value := range values {
+				if i, pW := h.settings.register(value); pW > 0 {
+					s.setIfGreater(h.settings, i, pW)
+				}
+			}
+			values = nil
 		}
+	}
+}
 
-		if estimator <= h.settings.largeEstimatorCutoff {
-			return uint64(math.Ceil(estimator))
-		}
+// AddBytes hashes the provided value with the configured Hasher and adds the
+// result to the Hll.  By default, the hash is the 64 bit variant of MurmurHash3
+// (seed 0), which is bit-identical to murmur3.Sum64 from
+// https://github.com/spaolacci/murmur3, so Hlls populated with this method can
+// be unioned with Hlls populated via AddRaw using that hash.
+func (h *Hll) AddBytes(value []byte) {
+	h.initOrPanic()
+	h.AddRaw(h.settings.effectiveHasher().Sum64(value))
+}
+
+// AddString hashes the bytes of the provided string in the same manner as
+// AddBytes and adds the result to the Hll.
+func (h *Hll) AddString(value string) {
+	h.AddBytes([]byte(value))
+}
+
+// AddInt64 hashes the provided value as 8 little endian bytes in the same
+// manner as AddBytes and adds the result to the Hll.
+func (h *Hll) AddInt64(value int64) {
+	h.AddUint64(uint64(value))
+}
+
+// AddUint64 hashes the provided value as 8 little endian bytes in the same
+// manner as AddBytes and adds the result to the Hll.  Unlike AddRaw, the value
+// does not need to have been hashed beforehand.
+func (h *Hll) AddUint64(value uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	h.AddBytes(buf[:])
+}
+
+// Cardinality estimates the number of values that have been added to this Hll
+// using the Estimator configured in the Hll's Settings.
+func (h *Hll) Cardinality() uint64 {
+	h.initOrPanic()
+	return h.cardinality(h.settings.estimator)
+}
+
+// CardinalityWith estimates the number of values that have been added to this
+// Hll using the provided Estimator instead of the one configured in the Hll's
+// Settings.  It will panic if the Estimator is not one of the defined values.
+func (h *Hll) CardinalityWith(estimator Estimator) uint64 {
+	h.initOrPanic()
+	return h.cardinality(estimator)
+}
+
+func (h *Hll) cardinality(estimator Estimator) uint64 {
+	return ceilUint64(h.estimateCardinality(estimator))
+}
+
+// ceilUint64 rounds the estimate up to a whole number.  When every register is
+// saturated, the estimate can be infinite or NaN, and converting those or any
+// other estimate beyond the range of a uint64 is implementation-defined, so
+// they're clamped to math.MaxUint64.
+func ceilUint64(estimate float64) uint64 {
+	estimate = math.Ceil(estimate)
+	if !(estimate < math.MaxUint64) {
+		return math.MaxUint64
+	}
+	return uint64(estimate)
+}
 
-		// following documentation courtesy of the java implementation:
-		// The "large range correction" formula from the HyperLogLog algorithm,
-		// adapted for 64 bit hashes. Only appropriate for estimators whose
-		// value exceeds the calculated cutoff.
-		largeEstimator := -1 * h.settings.twoToL * math.Log(1.0-(estimator/h.settings.twoToL))
-		return uint64(math.Ceil(largeEstimator))
+// estimateCardinality returns the unrounded estimate of the cardinality.  It
+// is exact for the explicit representation.
+func (h *Hll) estimateCardinality(estimator Estimator) float64 {
 
+	switch s := h.storage.(type) {
+	case explicitStorage:
+		return float64(len(s))
+	case registers:
+		return estimate(estimator, h.settings, s)
 	default:
 		// nil case.
 		return 0
 	}
 }
 
+// CardinalityEstimate estimates the number of values that have been added to
+// this Hll along with its standard error and the bounds of a two-sided
+// confidence interval at the provided confidence level, which must be between 0
+// and 1 exclusive (e.g. 0.95).  Unlike Cardinality, the estimate is not rounded.
+//
+// The explicit representation is exact, so its standard error is zero.  The
+// sparse representation and the other estimates in the linear counting range
+// have the error of linear counting, which is much lower than the error of the
+// HyperLogLog estimate.  Otherwise, the relative standard error is
+// 1.04/sqrt(m), which grows once the estimate approaches the number of distinct
+// hashes that log2m and regwidth can represent.  When every register is
+// saturated, the estimate and its error are infinite and the lower bound is 0.
+func (h *Hll) CardinalityEstimate(confidence float64) (Estimate, error) {
+	h.initOrPanic()
+
+	if !(confidence > 0 && confidence < 1) {
+		return Estimate{}, fmt.Errorf("confidence must be between 0 and 1 exclusive.  Got %v", confidence)
+	}
+
+	value := h.estimateCardinality(h.settings.estimator)
+
+	var stdError float64
+	if s, ok := h.storage.(registers); ok {
+		stdError = standardError(h.settings.estimator, h.settings, s, value)
+	}
+
+	// an unbounded error, e.g. when every register is saturated, says nothing
+	// about the lower bound.
+	z := math.Sqrt2 * math.Erfinv(confidence)
+	lower := 0.0
+	if !math.IsInf(stdError, 1) {
+		lower = math.Max(0, value-z*stdError)
+	}
+
+	return Estimate{
+		Value:    value,
+		StdError: stdError,
+		Lower:    lower,
+		Upper:    value + z*stdError,
+	}, nil
+}
+
 // Union will calculate the union of this Hll and the other Hll and store the
 // results into the receiver.
 //
 // Unlike StrictUnion, it allows unions between Hlls with different settings to
 // be combined, though doing so is not recommended because it will result in a
-// loss of accuracy.
+// loss of accuracy.  If the other Hll has a larger log2m, its registers are
+// folded down to match this one's.  If it has a smaller log2m, then this Hll
+// is first folded down to the other's log2m, which changes its Settings.
+// Register values that don't fit within this Hll's regwidth are clamped to the
+// maximum value.
 //
 // As long as your application uses a single group of settings, it is safe to
 // use this function.  If there is a possibility that you may union two Hlls
@@ -254,12 +398,227 @@ func (h *Hll) Union(other Hll) {
 // StrictUnion will calculate the union of this Hll and the other Hll and store
 // the results into the receiver.  It will return an error if the two Hlls are
 // not compatible where compatibility is defined as having the same register
-// width and log2m.  explicit and sparse thresholds don't factor into
+// width, log2m, and hasher.  explicit and sparse thresholds don't factor into
 // compatibility.
 func (h *Hll) StrictUnion(other Hll) error {
 	return h.union(other, true)
 }
 
+// UnionBytes calculates the union of dst and the Hll serialized in src and
+// stores the result into dst.  It is equivalent to calling FromBytes followed
+// by Union, but when the serialized Hll has the same log2m and regwidth as dst,
+// its values are read directly from src into dst's storage without allocating
+// an intermediate Hll.  Otherwise, it falls back to FromBytes and Union.
+//
+// Since the serialized form doesn't record a Hasher, no hasher compatibility
+// check is performed, and an Hll whose values were hashed with a different
+// Hasher than dst's is merged silently, which corrupts the estimate.  Callers
+// that store Hlls built with different Hashers must record the Hasher next to
+// the serialized form themselves.  It returns the same errors as FromBytes if
+// src is invalid, in which case dst is unmodified.
+func UnionBytes(dst *Hll, src []byte) error {
+	dst.initOrPanic()
+
+	storageType, settings, err := parseHeader(src)
+	if err != nil {
+		return err
+	}
+
+	if settings.log2m != dst.settings.log2m || settings.regwidth != dst.settings.regwidth {
+		other, err := FromBytes(src)
+		if err != nil {
+			return err
+		}
+		dst.Union(other)
+		return nil
+	}
+
+	bytes := src[3:]
+	switch storageType {
+	case explicit:
+		// like explicitStorage.fromBytes, but the values are added as they're
+		// read.
+		if len(bytes)%8 != 0 {
+			return ErrInsufficientBytes
+		}
+		// like union, an empty dst copies the explicit storage even if there
+		// are no values, and dst is upgraded if it's already over capacity.
+		if dst.storage == nil {
+			dst.storage = make(explicitStorage)
+		}
+		for i := 0; i < len(bytes); i += 8 {
+			dst.AddRaw(binary.BigEndian.Uint64(bytes[i : i+8]))
+		}
+		if dst.storage.overCapacity(dst.settings) {
+			dst.upgrade()
+		}
+	case sparse:
+		dst.unionSparseBytes(bytes)
+	case dense:
+		return dst.unionDenseBytes(bytes)
+	}
+
+	return nil
+}
+
+// unionSparseBytes unions the serialized sparse registers into the receiver,
+// which must have the same settings.  It mirrors union for sparseStorage.
+func (h *Hll) unionSparseBytes(bytes []byte) {
+
+	bitsPerRegister := h.settings.regwidth + h.settings.log2m
+	numRegisters := (8 * len(bytes)) / bitsPerRegister
+
+	// like union, the representation is promoted even if there are no
+	// registers.
+	var explicitValues explicitStorage
+	switch s := h.storage.(type) {
+	case nil:
+		h.bootstrapRegisters()
+	case explicitStorage:
+		explicitValues = s
+		h.bootstrapRegisters()
+	}
+
+	s := h.storage.(registers)
+	for i := 0; i < numRegisters; i++ {
+		regAndVal := readBits(bytes, i*bitsPerRegister, bitsPerRegister)
+		s.setIfGreater(h.settings, int(regAndVal>>uint(h.settings.regwidth)), byte(regAndVal&h.settings.regwidthMask))
+	}
+	h.addFromExplicit(explicitValues)
+
+	if h.storage.overCapacity(h.settings) {
+		h.upgrade()
+	}
+}
+
+// denseChunkRegisters is the number of registers that unionDenseBytes decodes
+// at a time.  It must be a multiple of 64 so that each chunk is a whole number
+// of words.
+const denseChunkRegisters = 1024
+
+// unionDenseBytes unions the serialized dense registers into the receiver,
+// which must have the same settings.  It mirrors union for denseStorage.
+func (h *Hll) unionDenseBytes(bytes []byte) error {
+
+	if len(bytes) != denseSizeInBytes(h.settings) {
+		return ErrInsufficientBytes
+	}
+
+	switch s := h.storage.(type) {
+	case nil, explicitStorage:
+		// there's nothing to union into...read the registers directly.
+		ds := newDenseStorage(h.settings)
+		if err := ds.fromBytes(h.settings, bytes); err != nil {
+			return err
+		}
+		h.storage = ds
+		if explicitValues, ok := s.(explicitStorage); ok {
+			h.addFromExplicit(explicitValues)
+		}
+		return nil
+	case sparseStorage:
+		h.upgrade()
+	}
+
+	// union the registers in chunks of denseChunkRegisters, which is always a
+	// whole number of words.  the words are decoded into a buffer on the stack
+	// to avoid allocating.
+	ds := h.storage.(denseStorage)
+	var buf [denseChunkRegisters * maximumRegwidthParam / 64]uint64
+
+	numReg := 1 << uint(h.settings.log2m)
+	for regnum := 0; regnum < numReg; regnum += denseChunkRegisters {
+
+		n := numReg - regnum
+		if n > denseChunkRegisters {
+			n = denseChunkRegisters
+		}
+
+		offset := regnum * h.settings.regwidth / 8
+		chunkBytes := bytes[offset : offset+divideBy8RoundUp(n*h.settings.regwidth)]
+		chunk := denseStorage(buf[:divideBy8RoundUp(len(chunkBytes))])
+
+		for i := range chunk {
+			if len(chunkBytes) >= 8 {
+				chunk[i] = binary.BigEndian.Uint64(chunkBytes)
+				chunkBytes = chunkBytes[8:]
+				continue
+			}
+
+			// the final partial word.
+			chunk[i] = 0
+			for j, b := range chunkBytes {
+				chunk[i] |= uint64(b) << uint(56-8*j)
+			}
+		}
+
+		ds[regnum*h.settings.regwidth/64:].unionRegisters(h.settings, chunk, n)
+	}
+
+	return nil
+}
+
+// Reduce returns a copy of this Hll with the provided settings, which may have
+// a smaller log2m or regwidth.  The result is equivalent to an Hll with the
+// provided settings that had the same values added to it, except that register
+// values which were clamped by this Hll's regwidth remain clamped.  The
+// receiver is not modified.
+//
+// It will return an error if the settings are invalid, if they have a larger
+// log2m or regwidth than this Hll, or if they have a different Hasher.
+func (h *Hll) Reduce(s Settings) (Hll, error) {
+	h.initOrPanic()
+
+	settings, err := s.toInternal()
+	if err != nil {
+		return Hll{}, err
+	}
+
+	if settings.log2m > h.settings.log2m {
+		return Hll{}, fmt.Errorf("cannot Reduce Log2m from %d to %d", h.settings.log2m, settings.log2m)
+	}
+	if settings.regwidth > h.settings.regwidth {
+		return Hll{}, fmt.Errorf("cannot Reduce Regwidth from %d to %d", h.settings.regwidth, settings.regwidth)
+	}
+	if settings.effectiveHasher() != h.settings.effectiveHasher() {
+		return Hll{}, ErrIncompatible
+	}
+
+	reduced := Hll{settings: settings}
+	if err := reduced.union(*h, false); err != nil {
+		return Hll{}, err
+	}
+
+	return reduced, nil
+}
+
+// convertedRegisters returns the values of the registers of this Hll when
+// converted to the provided log2m and regwidth along with whether they are all
+// zero.  Like Reduce, explicit values are converted to registers, registers are
+// folded if the log2m is smaller, and values are clamped if the regwidth is
+// smaller.  It's used when exporting to formats that only support registers.
+func (h *Hll) convertedRegisters(log2m, regwidth int) ([]byte, bool) {
+
+	// the hasher is irrelevant since no values are hashed.
+	settings, _ := Settings{Log2m: log2m, Regwidth: regwidth}.toInternal()
+	converted := Hll{settings: settings}
+	_ = converted.union(*h, false)
+
+	registers := make([]byte, 1<<uint(log2m))
+	dense, ok := converted.storage.(denseStorage)
+	if !ok {
+		return registers, true
+	}
+
+	empty := true
+	for regnum := range registers {
+		registers[regnum] = dense.get(regnum, regwidth)
+		empty = empty && registers[regnum] == 0
+	}
+
+	return registers, empty
+}
+
 func (h *Hll) union(other Hll, strict bool) error {
 
 	// this is kind of an ugly method...this is where the abstraction of storage
@@ -269,9 +628,7 @@ func (h *Hll) union(other Hll, strict bool) error {
 	h.initOrPanic()
 	other.initOrPanic()
 
-	sameSettings := h.settings.regwidth == other.settings.regwidth && h.settings.log2m == other.settings.log2m
-
-	if strict && !sameSettings {
+	if strict && !h.compatible(other) {
 		return ErrIncompatible
 	}
 
@@ -280,6 +637,22 @@ func (h *Hll) union(other Hll, strict bool) error {
 		return nil
 	}
 
+	// if the other's registers are incompatible with this one's, fold them into
+	// registers that are.  if the other has fewer registers, then this one
+	// must first be folded down to match.  explicit values are raw hashes, so
+	// they can be added regardless of the settings.
+	if otherRegisters, ok := other.storage.(registers); ok && !h.sameRegisters(other) {
+		if other.settings.log2m < h.settings.log2m {
+			if err := h.fold(other.settings.log2m); err != nil {
+				return err
+			}
+		}
+		other = Hll{
+			settings: h.settings,
+			storage:  foldRegisters(other.settings, h.settings, otherRegisters),
+		}
+	}
+
 	// if this one is empty, deep copy the other's storage.
 	if h.storage == nil {
 		// there's an edge case if sparse is disabled but the other is sparse.
@@ -296,6 +669,11 @@ func (h *Hll) union(other Hll, strict bool) error {
 		} else {
 			h.storage = other.storage.copy()
 		}
+
+		// the other may have been over capacity for this one's settings.
+		if h.storage.overCapacity(h.settings) {
+			h.upgrade()
+		}
 		return nil
 	}
 
@@ -323,11 +701,6 @@ func (h *Hll) union(other Hll, strict bool) error {
 			// over the sparse storage and copy over
 			// larger register values.
 			for k, v := range otherStorage {
-				// ensure that the value fits within the sparse storage's
-				// register.  it's possible that the value may be greater than
-				// the max register value in the case of a non-strict union
-				// where the other has wider registers.
-				v = v & byte(h.settings.mBitsMask)
 				thisStorage.setIfGreater(h.settings, int(k), v)
 			}
 		}
@@ -342,9 +715,9 @@ func (h *Hll) union(other Hll, strict bool) error {
 			// if this hll is sparse, then upgrade it to a dense hll and then do
 			// a dense union.
 			h.upgrade()
-			denseUnion(h.storage.(denseStorage), otherStorage, h.settings, other.settings)
+			h.storage.(denseStorage).union(h.settings, otherStorage)
 		case denseStorage:
-			denseUnion(thisStorage, otherStorage, h.settings, other.settings)
+			thisStorage.union(h.settings, otherStorage)
 		}
 	}
 
@@ -360,9 +733,41 @@ func (h *Hll) union(other Hll, strict bool) error {
 // ToBytes returns a byte slice with the serialized Hll value per the storage
 // spec https://github.com/aggregateknowledge/hll-storage-spec/blob/master/STORAGE.md.
 func (h *Hll) ToBytes() []byte {
+	return h.AppendBytes(nil)
+}
+
+// AppendBytes appends the serialized Hll to dst and returns the extended slice.
+// The result is the same as append(dst, h.ToBytes()...), but no intermediate
+// buffer is allocated, and dst is not reallocated if it has sufficient
+// capacity.
+func (h *Hll) AppendBytes(dst []byte) []byte {
 
 	h.initOrPanic()
 
+	bytesNeeded := 0
+
+	if h.storage != nil {
+		bytesNeeded = h.storage.sizeInBytes(h.settings)
+	}
+
+	start := len(dst)
+	dst = growZeroed(dst, 3 /*header bytes*/ +bytesNeeded)
+
+	header := h.header()
+	copy(dst[start:], header[:])
+
+	if h.storage != nil {
+		// NOTE : the capacity is limited because the storage may size its
+		//        output by the capacity of the slice.
+		h.storage.writeBytes(h.settings, dst[start+3:len(dst):len(dst)])
+	}
+
+	return dst
+}
+
+// header returns the 3 header bytes of the serialized Hll.
+func (h *Hll) header() [3]byte {
+
 	var storageType storageType
 
 	switch h.storage.(type) {
@@ -376,23 +781,11 @@ func (h *Hll) ToBytes() []byte {
 		storageType = empty
 	}
 
-	bytesNeeded := 0
-
-	if h.storage != nil {
-		bytesNeeded = h.storage.sizeInBytes(h.settings)
-	}
-
-	bytes := make([]byte, 3 /*header bytes*/ +bytesNeeded)
-
-	bytes[0] = (1 << 4) | byte(storageType)
-	bytes[1] = byte(((h.settings.regwidth - 1) << 5) | h.settings.log2m)
-	bytes[2] = packCutoffByte(h.settings)
-
-	if h.storage != nil {
-		h.storage.writeBytes(h.settings, bytes[3:])
+	return [3]byte{
+		(1 << 4) | byte(storageType),
+		byte(((h.settings.regwidth - 1) << 5) | h.settings.log2m),
+		packCutoffByte(h.settings),
 	}
-
-	return bytes
 }
 
 // Clear resets this Hll.  Unlike other implementations that leave the backing
@@ -404,6 +797,37 @@ func (h *Hll) Clear() {
 	h.storage = nil
 }
 
+// sameRegisters returns true if the other Hll has the same number and width of
+// registers as this one.
+func (h *Hll) sameRegisters(other Hll) bool {
+	return h.settings.regwidth == other.settings.regwidth && h.settings.log2m == other.settings.log2m
+}
+
+// fold reduces the number of registers in this Hll to 2^log2m, which must not
+// be larger than the current number.  See foldRegisters.
+func (h *Hll) fold(log2m int) error {
+
+	external := h.settings.toExternal()
+	external.Log2m = log2m
+	settings, err := external.toInternal()
+	if err != nil {
+		return err
+	}
+
+	if s, ok := h.storage.(registers); ok {
+		h.storage = foldRegisters(h.settings, settings, s)
+	}
+	h.settings = settings
+
+	return nil
+}
+
+// compatible returns true if the other Hll has the same regwidth, log2m, and
+// hasher as this one.  Both Hlls must have been initialized.
+func (h *Hll) compatible(other Hll) bool {
+	return h.sameRegisters(other) && h.settings.effectiveHasher() == other.settings.effectiveHasher()
+}
+
 // initOrPanic is used to lazily initialize a zero value to an empty Hll (in the
 // presence of default settings) or to panic if the operation is being evaluated
 // against an undefined Hll.  If there are no default settings, the zero value
@@ -424,6 +848,42 @@ func (h *Hll) initOrPanic() {
 	h.settings = defaults
 }
 
+// bootstrapRegisters replaces the storage with empty probabilistic storage of
+// the type that would be used after upgrading from explicit storage.
+func (h *Hll) bootstrapRegisters() {
+	if h.settings.sparseEnabled {
+		h.storage = make(sparseStorage)
+	} else {
+		h.storage = newDenseStorage(h.settings)
+	}
+}
+
+// setRegister sets the register to the value if it's greater than the current
+// value, bootstrapping the registers if the Hll is empty.  It's used when
+// importing registers from other formats, and the caller is responsible for
+// upgrading the storage once all registers have been set.
+func (h *Hll) setRegister(regnum int, value byte) {
+	if value == 0 {
+		return
+	}
+	if h.storage == nil {
+		h.bootstrapRegisters()
+	}
+	h.storage.(registers).setIfGreater(h.settings, regnum, value)
+}
+
+// bootstrap allocates the initial storage for an empty Hll depending on the
+// configured settings.
+func (h *Hll) bootstrap() {
+	if h.settings.explicitThreshold > 0 {
+		h.storage = make(explicitStorage)
+	} else if h.settings.sparseEnabled {
+		h.storage = make(sparseStorage)
+	} else {
+		h.storage = newDenseStorage(h.settings)
+	}
+}
+
 // upgrade will bump up the storage to the next tier depending on the configured
 // settings.  It's assumed that the current storage has already been verified to
 // be over capacity.
@@ -439,11 +899,7 @@ func (h *Hll) upgrade() {
 	// upgrade paths being requested.
 	switch s := h.storage.(type) {
 	case explicitStorage:
-		if h.settings.sparseEnabled {
-			h.storage = make(sparseStorage)
-		} else {
-			h.storage = newDenseStorage(h.settings)
-		}
+		h.bootstrapRegisters()
 
 		for value := range s {
 			h.AddRaw(value)
@@ -474,24 +930,56 @@ func sparseToDense(settings *settings, sparse sparseStorage) denseStorage {
 	return dense
 }
 
-// denseUnion handles union-ing two denseStorage instances.  In case the two
-// settings have compatible regwidth and log2m settings, the efficient
-// single-pass dense union will be used.  If they differ, then register values
-// will be compared one-by-one, taking the largest value for each.
-func denseUnion(thisStorage, otherStorage denseStorage, thisSettings, otherSettings *settings) {
-	// if the settings are compatible, use the optimized union function.
-	// otherwise, loop over each register and call get on other and setIfGreater
-	// on this.
-	if thisSettings.log2m == otherSettings.log2m && thisSettings.regwidth == otherSettings.regwidth {
-		thisStorage.union(thisSettings, otherStorage)
+// foldRegisters converts the registers of an Hll with the from settings into
+// storage for an Hll with the to settings, which must not have a larger log2m.
+// The result is the same as if the original hashed values had been added to an
+// Hll with the to settings.
+//
+// When log2m is reduced, the upper from.log2m - to.log2m bits of a register's
+// index become the lowest bits of the value used to compute its register
+// value.  If any of those bits are set, the register value is one plus the
+// number of trailing zeros among them.  Otherwise, the original register value
+// is offset by the number of extra bits.  Register values that don't fit into
+// the to regwidth are clamped to the maximum value.
+func foldRegisters(from, to *settings, s registers) storage {
+
+	var folded storage
+	if _, ok := s.(sparseStorage); ok && to.sparseEnabled {
+		folded = make(sparseStorage)
 	} else {
-		for i := 0; i < 1<<uint(thisSettings.log2m); i++ {
-			// mask the other's register value with our mBits setting to ensure
-			// an accurate comparison.
-			regVal := otherStorage.get(i, otherSettings.log2m) & byte(thisSettings.mBitsMask)
-			thisStorage.setIfGreater(thisSettings, i, regVal)
+		folded = newDenseStorage(to)
+	}
+	foldedRegisters := folded.(registers)
+
+	extraBits := from.log2m - to.log2m
+	fold := func(regnum int, value byte) {
+		if value == 0 {
+			return
+		}
+
+		foldedValue := int(value) + extraBits
+		if upperBits := regnum >> uint(to.log2m); upperBits != 0 {
+			foldedValue = 1 + bits.TrailingZeros(uint(upperBits))
+		}
+		if foldedValue > int(to.regwidthMask) {
+			foldedValue = int(to.regwidthMask)
 		}
+
+		foldedRegisters.setIfGreater(to, regnum&int(to.mBitsMask), byte(foldedValue))
 	}
+
+	switch s := s.(type) {
+	case sparseStorage:
+		for regnum, value := range s {
+			fold(int(regnum), value)
+		}
+	case denseStorage:
+		for regnum := 0; regnum < 1<<uint(from.log2m); regnum++ {
+			fold(regnum, s.get(regnum, from.regwidth))
+		}
+	}
+
+	return folded
 }
 
 // packCutoffByte is a helper function to serialize the byte that contains
@@ -505,11 +993,11 @@ func packCutoffByte(settings *settings) byte {
 	} else if settings.explicitThreshold == 0 {
 		threshold = 0
 	} else {
-		// pack as an exponent of 2 per the spec.  note that this can be a
+		// pack as log2(threshold) + 1 per the spec.  note that this can be a
 		// destructive transformation if the threshold is not a power of 2.  in
 		// that case, this behaves the same as the java library where it rounds
 		// down.
-		threshold = byte(bits.Len32(uint32(settings.explicitThreshold))) - 1
+		threshold = byte(bits.Len32(uint32(settings.explicitThreshold)))
 	}
 
 	cutoff := threshold
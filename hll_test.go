@@ -1,6 +1,10 @@
 package hll
 
 import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"math/rand"
 	"reflect"
 	"testing"
@@ -39,6 +43,30 @@ func Test_ZeroValue_NoDefaultSettings(t *testing.T) {
 			label: "ToBytes",
 			op:    func(hll Hll) { hll.ToBytes() },
 		},
+		{
+			label: "AppendBytes",
+			op:    func(hll Hll) { hll.AppendBytes(nil) },
+		},
+		{
+			label: "WriteTo",
+			op:    func(hll Hll) { _, _ = hll.WriteTo(io.Discard) },
+		},
+		{
+			label: "ToRedis",
+			op:    func(hll Hll) { _, _ = hll.ToRedis() },
+		},
+		{
+			label: "ToDataSketches",
+			op:    func(hll Hll) { _, _ = hll.ToDataSketches(DataSketchesHLL8) },
+		},
+		{
+			label: "ToZetaSketch",
+			op:    func(hll Hll) { _, _ = hll.ToZetaSketch() },
+		},
+		{
+			label: "ToAirlift",
+			op:    func(hll Hll) { _, _ = hll.ToAirlift() },
+		},
 		{
 			label: "Clear",
 			op:    func(hll Hll) { hll.Clear() },
@@ -162,6 +190,30 @@ func Test_Undefined(t *testing.T) {
 			label: "ToBytes",
 			op:    func(hll Hll) { hll.ToBytes() },
 		},
+		{
+			label: "AppendBytes",
+			op:    func(hll Hll) { hll.AppendBytes(nil) },
+		},
+		{
+			label: "WriteTo",
+			op:    func(hll Hll) { _, _ = hll.WriteTo(io.Discard) },
+		},
+		{
+			label: "ToRedis",
+			op:    func(hll Hll) { _, _ = hll.ToRedis() },
+		},
+		{
+			label: "ToDataSketches",
+			op:    func(hll Hll) { _, _ = hll.ToDataSketches(DataSketchesHLL8) },
+		},
+		{
+			label: "ToZetaSketch",
+			op:    func(hll Hll) { _, _ = hll.ToZetaSketch() },
+		},
+		{
+			label: "ToAirlift",
+			op:    func(hll Hll) { _, _ = hll.ToAirlift() },
+		},
 		{
 			label: "Clear",
 			op:    func(hll Hll) { hll.Clear() },
@@ -186,6 +238,80 @@ func Test_Undefined(t *testing.T) {
 	}
 }
 
+// Test_AddHashed ensures that the hashing add functions produce the same
+// result as adding the MurmurHash3 value with AddRaw.
+func Test_AddHashed(t *testing.T) {
+
+	settings := Settings{
+		Log2m:             11,
+		Regwidth:          5,
+		ExplicitThreshold: AutoExplicitThreshold,
+		SparseEnabled:     true,
+	}
+
+	expected := newHll(t, settings)
+	expected.AddRaw(0xcbd8a7b341bd9b02) // murmur3 of "hello"
+	expected.AddRaw(murmur3Sum64([]byte{0x15, 0xcd, 0x5b, 0x07, 0, 0, 0, 0}))
+	expected.AddRaw(murmur3Sum64([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}))
+
+	{
+		hll := newHll(t, settings)
+		hll.AddString("hello")
+		hll.AddInt64(123456789)
+		hll.AddInt64(-1)
+		assert.Equal(t, expected.ToBytes(), hll.ToBytes())
+	}
+	{
+		hll := newHll(t, settings)
+		hll.AddBytes([]byte("hello"))
+		hll.AddUint64(123456789)
+		hll.AddUint64(0xffffffffffffffff)
+		assert.Equal(t, expected.ToBytes(), hll.ToBytes())
+	}
+}
+
+// Test_Hasher ensures that the configured Hasher is used by the hashing add
+// functions and that StrictUnion refuses to combine Hlls with different
+// hashers.
+func Test_Hasher(t *testing.T) {
+
+	settings := Settings{
+		Log2m:             11,
+		Regwidth:          5,
+		ExplicitThreshold: AutoExplicitThreshold,
+		SparseEnabled:     true,
+	}
+
+	fnvSettings := settings
+	fnvSettings.Hasher = fnvHasher{}
+
+	hll := newHll(t, fnvSettings)
+	assert.Equal(t, fnvHasher{}, hll.Settings().Hasher)
+
+	hll.AddString("hello")
+	hll.AddInt64(42)
+
+	expected := newHll(t, settings)
+	expected.AddRaw(fnvHasher{}.Sum64([]byte("hello")))
+	expected.AddRaw(fnvHasher{}.Sum64([]byte{42, 0, 0, 0, 0, 0, 0, 0}))
+	assert.Equal(t, expected.storage, hll.storage)
+
+	// the default hasher may be declared explicitly or left nil.
+	murmurSettings := settings
+	murmurSettings.Hasher = Murmur3Hasher
+
+	defaultHll := newHll(t, settings)
+	require.NoError(t, defaultHll.StrictUnion(newHll(t, murmurSettings)))
+	require.Equal(t, ErrIncompatible, defaultHll.StrictUnion(hll))
+	require.Equal(t, ErrIncompatible, hll.StrictUnion(newHll(t, murmurSettings)))
+	require.NoError(t, hll.StrictUnion(newHll(t, fnvSettings)))
+
+	// a non-strict union is permitted.
+	union := newHll(t, settings)
+	union.Union(hll)
+	assert.Equal(t, expected.storage, union.storage)
+}
+
 // Test_UpgradePaths ensures that the Hll upgrades storage as elements are added
 // to the Hll per the specification and the configuration settings.
 func Test_UpgradePaths(t *testing.T) {
@@ -372,6 +498,60 @@ func Test_UpgradePaths(t *testing.T) {
 	}
 }
 
+// Test_AddRawBatch ensures that adding values in a batch produces exactly the
+// same Hll as adding them one at a time, including across storage upgrades.
+func Test_AddRawBatch(t *testing.T) {
+
+	rand.Seed(1234567890)
+
+	settings := []Settings{
+		{Log2m: 8, Regwidth: 4, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+		{Log2m: 10, Regwidth: 4, ExplicitThreshold: 100, SparseEnabled: false},
+		{Log2m: 10, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: true},
+		{Log2m: 11, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false},
+	}
+
+	for _, s := range settings {
+		for _, n := range []int{0, 1, 50, 101, 1000, 10000} {
+			t.Run(fmt.Sprintf("%+v/%d", s, n), func(t *testing.T) {
+
+				values := make([]uint64, n)
+				for i := range values {
+					// sprinkle in some zeros and duplicates.
+					switch rand.Intn(10) {
+					case 0:
+						values[i] = 0
+					case 1:
+						values[i] = values[rand.Intn(i+1)]
+					default:
+						values[i] = rand.Uint64()
+					}
+				}
+
+				expected := newHll(t, s)
+				for _, value := range values {
+					expected.AddRaw(value)
+				}
+
+				// add in a couple of chunks to make sure that batches can
+				// start from any storage type.
+				actual := newHll(t, s)
+				actual.AddRawBatch(values[:n/3])
+				actual.AddRawBatch(values[n/3:])
+
+				assert.Equal(t, reflect.TypeOf(expected.storage), reflect.TypeOf(actual.storage))
+				assert.Equal(t, expected.storage, actual.storage)
+				assert.Equal(t, expected.Cardinality(), actual.Cardinality())
+			})
+		}
+	}
+
+	// a batch of zeros should leave the Hll empty.
+	hll := newHll(t, settings[0])
+	hll.AddRawBatch([]uint64{0, 0, 0})
+	assertEmpty(t, hll)
+}
+
 // Test_MismatchedStorageUnions exercises the different possible cases when
 // unioning Hlls with different storage types.
 func Test_MismatchedStorageUnions(t *testing.T) {
@@ -607,6 +787,352 @@ func Test_MismatchedStorageUnions(t *testing.T) {
 	}
 }
 
+// Test_MixedSettingsUnion ensures that a non-strict union of Hlls with
+// different log2m and regwidth settings produces the same registers as adding
+// the original values to an Hll with the resulting settings.
+func Test_MixedSettingsUnion(t *testing.T) {
+
+	tests := []struct {
+		settings1, settings2 Settings
+		n1, n2               int
+	}{
+		// other has more registers.
+		{Settings{Log2m: 11, Regwidth: 5, SparseEnabled: true}, Settings{Log2m: 14, Regwidth: 5, SparseEnabled: true}, 5000, 5000},
+		{Settings{Log2m: 11, Regwidth: 5, SparseEnabled: true}, Settings{Log2m: 14, Regwidth: 5, SparseEnabled: true}, 10, 100},
+		{Settings{Log2m: 11, Regwidth: 5, SparseEnabled: false}, Settings{Log2m: 14, Regwidth: 5, SparseEnabled: true}, 0, 100},
+		{Settings{Log2m: 4, Regwidth: 6}, Settings{Log2m: 10, Regwidth: 6}, 1000, 1000},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold}, Settings{Log2m: 14, Regwidth: 6}, 10, 100000},
+
+		// other has fewer registers...this one is folded.
+		{Settings{Log2m: 14, Regwidth: 5, SparseEnabled: true}, Settings{Log2m: 11, Regwidth: 5, SparseEnabled: true}, 5000, 5000},
+		{Settings{Log2m: 14, Regwidth: 4, SparseEnabled: true}, Settings{Log2m: 11, Regwidth: 6}, 100, 100000},
+
+		// different regwidth...values are clamped.
+		{Settings{Log2m: 11, Regwidth: 3}, Settings{Log2m: 11, Regwidth: 8}, 1000, 1000000},
+		{Settings{Log2m: 11, Regwidth: 3, SparseEnabled: true}, Settings{Log2m: 11, Regwidth: 8, SparseEnabled: true}, 100, 100},
+		{Settings{Log2m: 5, Regwidth: 4}, Settings{Log2m: 7, Regwidth: 8}, 1000, 1000},
+	}
+
+	// NOTE : a union can't recover register values that were clamped by a
+	//        narrower regwidth in the other Hll, so those cases are omitted.
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%+v/%+v/%d/%d", tt.settings1, tt.settings2, tt.n1, tt.n2), func(t *testing.T) {
+
+			r := rand.New(rand.NewSource(1234567890))
+
+			hll1, hll2 := newHll(t, tt.settings1), newHll(t, tt.settings2)
+			var values []uint64
+			for i := 0; i < tt.n1+tt.n2; i++ {
+				value := r.Uint64()
+				values = append(values, value)
+				if i < tt.n1 {
+					hll1.AddRaw(value)
+				} else {
+					hll2.AddRaw(value)
+				}
+			}
+
+			hll1.Union(hll2)
+
+			expectedSettings := tt.settings1
+			if tt.settings2.Log2m < expectedSettings.Log2m {
+				expectedSettings.Log2m = tt.settings2.Log2m
+			}
+			assert.Equal(t, expectedSettings, hll1.Settings())
+
+			expected := newHll(t, expectedSettings)
+			expected.AddRawBatch(values)
+
+			assert.Equal(t, registerValues(expected), registerValues(hll1))
+			assert.Equal(t, expected.Cardinality(), hll1.Cardinality())
+		})
+	}
+}
+
+// Test_Reduce ensures that reducing an Hll produces the same registers as
+// adding the original values to an Hll with the reduced settings.
+func Test_Reduce(t *testing.T) {
+
+	from := Settings{Log2m: 14, Regwidth: 6, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, to := range []Settings{
+		{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+		{Log2m: 11, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false},
+		{Log2m: 14, Regwidth: 4, ExplicitThreshold: 0, SparseEnabled: true},
+		{Log2m: 4, Regwidth: 1},
+	} {
+		for _, n := range []int{0, 10, 1000, 100000} {
+			t.Run(fmt.Sprintf("%+v/%d", to, n), func(t *testing.T) {
+
+				r := rand.New(rand.NewSource(int64(n)))
+				values := make([]uint64, n)
+				for i := range values {
+					values[i] = r.Uint64()
+				}
+
+				hll := newHll(t, from)
+				hll.AddRawBatch(values)
+				original := hll.ToBytes()
+
+				reduced, err := hll.Reduce(to)
+				require.NoError(t, err)
+				assert.Equal(t, to, reduced.Settings())
+
+				expected := newHll(t, to)
+				expected.AddRawBatch(values)
+
+				assert.Equal(t, reflect.TypeOf(expected.storage), reflect.TypeOf(reduced.storage))
+				if _, ok := expected.storage.(explicitStorage); ok {
+					assert.Equal(t, expected.storage, reduced.storage)
+				} else {
+					assert.Equal(t, registerValues(expected), registerValues(reduced))
+				}
+				assert.Equal(t, expected.Cardinality(), reduced.Cardinality())
+
+				// the original is unmodified.
+				assert.Equal(t, original, hll.ToBytes())
+			})
+		}
+	}
+}
+
+func Test_Reduce_Errors(t *testing.T) {
+
+	hll := newHll(t, Settings{Log2m: 11, Regwidth: 5})
+
+	_, err := hll.Reduce(Settings{Log2m: 12, Regwidth: 5})
+	assert.Error(t, err)
+
+	_, err = hll.Reduce(Settings{Log2m: 11, Regwidth: 6})
+	assert.Error(t, err)
+
+	_, err = hll.Reduce(Settings{Log2m: 11, Regwidth: 5, Hasher: fnvHasher{}})
+	assert.Equal(t, ErrIncompatible, err)
+
+	_, err = hll.Reduce(Settings{Log2m: 1, Regwidth: 5})
+	assert.Error(t, err)
+}
+
+// Test_UnionBytes ensures that UnionBytes produces the same result as
+// FromBytes followed by Union for every combination of representations.
+func Test_UnionBytes(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	sizes := []int{0, 10, 100, 1000, 10000}
+
+	tests := []struct {
+		dstSettings, srcSettings Settings
+	}{
+		{settings, settings},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false}, settings},
+		{Settings{Log2m: 4, Regwidth: 8, ExplicitThreshold: 4, SparseEnabled: true}, Settings{Log2m: 4, Regwidth: 8, ExplicitThreshold: 4, SparseEnabled: true}},
+		{Settings{Log2m: 13, Regwidth: 3, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, Settings{Log2m: 13, Regwidth: 3, ExplicitThreshold: 0, SparseEnabled: true}},
+		// falls back to FromBytes and Union.
+		{settings, Settings{Log2m: 14, Regwidth: 6, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}},
+	}
+
+	for _, tt := range tests {
+		for _, dstSize := range sizes {
+			for _, srcSize := range sizes {
+				t.Run(fmt.Sprintf("%+v/%d/%+v/%d", tt.dstSettings, dstSize, tt.srcSettings, srcSize), func(t *testing.T) {
+
+					r := rand.New(rand.NewSource(int64(dstSize*100000 + srcSize)))
+
+					dst := newHll(t, tt.dstSettings)
+					for i := 0; i < dstSize; i++ {
+						dst.AddRaw(r.Uint64())
+					}
+					src := newHll(t, tt.srcSettings)
+					for i := 0; i < srcSize; i++ {
+						src.AddRaw(r.Uint64())
+					}
+
+					bytes := src.ToBytes()
+					other, err := FromBytes(bytes)
+					require.NoError(t, err)
+
+					expected := newHll(t, tt.dstSettings)
+					expected.Union(dst)
+					expected.Union(other)
+
+					require.NoError(t, UnionBytes(&dst, bytes))
+					assert.Equal(t, expected.Settings(), dst.Settings())
+					assert.Equal(t, reflect.TypeOf(expected.storage), reflect.TypeOf(dst.storage))
+					assert.Equal(t, expected.ToBytes(), dst.ToBytes())
+				})
+			}
+		}
+	}
+}
+
+func Test_UnionBytes_Errors(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, size := range []int{10, 10000} {
+		src := newHll(t, settings)
+		for i := 0; i < size; i++ {
+			src.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+		}
+		bytes := src.ToBytes()
+
+		dst := newHll(t, settings)
+		dst.AddRaw(1)
+		original := dst.ToBytes()
+
+		assert.Equal(t, ErrInsufficientBytes, UnionBytes(&dst, bytes[:len(bytes)-1]))
+		assert.Equal(t, ErrInsufficientBytes, UnionBytes(&dst, bytes[:2]))
+		assert.Equal(t, original, dst.ToBytes())
+	}
+
+	dst := newHll(t, settings)
+	assert.Error(t, UnionBytes(&dst, []byte{0x21, 0x8b, 0x7f}))
+	assert.Error(t, UnionBytes(&dst, []byte{0x15, 0x8b, 0x7f}))
+	assertEmpty(t, dst)
+}
+
+func Test_CutoffByte(t *testing.T) {
+
+	tests := []struct {
+		explicitThreshold int
+		sparseEnabled     bool
+		cutoff            byte
+		unpacked          int
+	}{
+		{AutoExplicitThreshold, true, 0x7f, AutoExplicitThreshold},
+		{0, false, 0x00, 0},
+		{1, true, 0x41, 1},
+		{4, false, 0x03, 4},
+		{100, true, 0x47, 64}, // not a power of 2, so it rounds down.
+		{maximumExplicitThreshold, false, 0x12, maximumExplicitThreshold},
+	}
+
+	for _, tt := range tests {
+		settings, err := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: tt.explicitThreshold, SparseEnabled: tt.sparseEnabled}.toInternal()
+		require.NoError(t, err)
+
+		cutoff := packCutoffByte(settings)
+		assert.Equal(t, tt.cutoff, cutoff, "threshold %d", tt.explicitThreshold)
+
+		sparseEnabled, explicitThreshold := unpackCutoffByte(cutoff)
+		assert.Equal(t, tt.sparseEnabled, sparseEnabled)
+		assert.Equal(t, tt.unpacked, explicitThreshold)
+	}
+}
+
+// Test_FromBytes_CutoffByteBeforeSpecFix pins how Hlls serialized by earlier
+// versions of this library read back.  Those versions wrote an explicit
+// threshold t as log2(t) rather than log2(t)+1.
+func Test_FromBytes_CutoffByteBeforeSpecFix(t *testing.T) {
+
+	// explicit threshold of 256 with sparse enabled, written as 0x48.
+	written := []byte{0x12, 0x8b, 0x48, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 2}
+
+	h, err := FromBytes(written)
+	require.NoError(t, err)
+	assertExplicit(t, h)
+	assert.Equal(t, 128, h.Settings().ExplicitThreshold)
+	assert.True(t, h.Settings().SparseEnabled)
+	assert.Equal(t, uint64(2), h.Cardinality())
+
+	// the halved threshold is written back unchanged from now on.
+	assert.Equal(t, written, h.ToBytes())
+
+	// an explicit threshold of 1 was written as 0, which disables the explicit
+	// representation.
+	h, err = FromBytes([]byte{0x11, 0x8b, 0x40})
+	require.NoError(t, err)
+	assert.Equal(t, 0, h.Settings().ExplicitThreshold)
+}
+
+// Test_FromBytes_DenseLength ensures that the length of dense storage is
+// checked before it's allocated, since the header alone could otherwise force
+// an allocation of 2^31 8 bit registers.
+func Test_FromBytes_DenseLength(t *testing.T) {
+
+	header := []byte{0x14, 0xff, 0x00}
+
+	_, err := FromBytes(header)
+	assert.Equal(t, ErrInsufficientBytes, err)
+
+	_, err = ReadHll(bytes.NewReader(header))
+	assert.Equal(t, ErrInsufficientBytes, err)
+
+	var h Hll
+	assert.Equal(t, ErrInsufficientBytes, h.Decode(header))
+}
+
+func BenchmarkUnionBytes(b *testing.B) {
+	for _, size := range []int{100, 1000, 100000} {
+		dst, bytes := benchmarkUnionBytesData(b, size)
+		b.Run(fmt.Sprintf("UnionBytes/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := UnionBytes(&dst, bytes); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("FromBytes+Union/%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				other, err := FromBytes(bytes)
+				if err != nil {
+					b.Fatal(err)
+				}
+				dst.Union(other)
+			}
+		})
+	}
+}
+
+// benchmarkUnionBytesData returns a dense Hll along with a serialized Hll with
+// size values added to it.
+func benchmarkUnionBytesData(b *testing.B, size int) (Hll, []byte) {
+
+	settings := Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	r := rand.New(rand.NewSource(1234567890))
+
+	dst, err := NewHll(settings)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < 100000; i++ {
+		dst.AddRaw(r.Uint64())
+	}
+
+	src, err := NewHll(settings)
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < size; i++ {
+		src.AddRaw(r.Uint64())
+	}
+
+	return dst, src.ToBytes()
+}
+
+// registerValues returns the value of every register in a probabilistic Hll.
+func registerValues(hll Hll) []byte {
+
+	m := 1 << uint(hll.settings.log2m)
+	values := make([]byte, m)
+
+	switch s := hll.storage.(type) {
+	case sparseStorage:
+		for regnum, value := range s {
+			values[regnum] = value
+		}
+	case denseStorage:
+		for regnum := range values {
+			values[regnum] = s.get(regnum, hll.settings.regwidth)
+		}
+	}
+
+	return values
+}
+
 func newHll(t *testing.T, settings Settings) Hll {
 	hll, err := NewHll(settings)
 	require.NoError(t, err)
@@ -628,3 +1154,56 @@ func assertSparse(t *testing.T, hll Hll) bool {
 func assertDense(t *testing.T, hll Hll) bool {
 	return assert.Equal(t, reflect.TypeOf(denseStorage{}), reflect.TypeOf(hll.storage), "expected dense storage")
 }
+
+// fnvHasher is a Hasher used to test the configurable hashing.
+type fnvHasher struct{}
+
+func (fnvHasher) Sum64(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// wrappedHasher is a Hasher of a comparable type that is only comparable when
+// the Hasher it wraps is.
+type wrappedHasher struct {
+	Hasher
+}
+
+// funcHasher is a Hasher that is not comparable.
+type funcHasher func([]byte) uint64
+
+func (f funcHasher) Sum64(data []byte) uint64 {
+	return f(data)
+}
+
+func BenchmarkAddRaw(b *testing.B) {
+	values := benchmarkValues(1 << 16)
+	hll, _ := NewHll(Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, value := range values {
+			hll.AddRaw(value)
+		}
+	}
+}
+
+func BenchmarkAddRawBatch(b *testing.B) {
+	values := benchmarkValues(1 << 16)
+	hll, _ := NewHll(Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hll.AddRawBatch(values)
+	}
+}
+
+func benchmarkValues(n int) []uint64 {
+	r := rand.New(rand.NewSource(1234567890))
+	values := make([]uint64, n)
+	for i := range values {
+		values[i] = r.Uint64()
+	}
+	return values
+}
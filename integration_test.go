@@ -5,7 +5,6 @@ import (
 	"compress/gzip"
 	"encoding/hex"
 	"io/ioutil"
-	"math"
 	"os"
 	"reflect"
 	"strconv"
@@ -39,6 +38,11 @@ func Test_IntegrationSuite(t *testing.T) {
 
 	for _, suites := range suites {
 
+		// the hash vectors are checked by Test_PostgresHashes.
+		if suites.Name() == "hash" {
+			continue
+		}
+
 		suiteDir := "integration_tests/" + suites.Name()
 		files, err := ioutil.ReadDir(suiteDir)
 		require.NoError(t, err)
@@ -134,7 +138,7 @@ func parseAddTestCase(t *testing.T, scanner *bufio.Scanner, lineNo int) addTestC
 	return addTestCase{
 		hll:         parseHll(t, parts[2], lineNo),
 		toAdd:       uint64(toAdd),
-		cardinality: uint64(math.Ceil(cardinality)),
+		cardinality: ceilUint64(cardinality),
 	}
 }
 
@@ -152,7 +156,7 @@ func parseUnionTestCase(t *testing.T, scanner *bufio.Scanner, lineNo int) unionT
 	return unionTestCase{
 		hll:         parseHll(t, parts[3], lineNo),
 		toUnion:     parseHll(t, parts[1], lineNo),
-		cardinality: uint64(math.Ceil(cardinality)),
+		cardinality: ceilUint64(cardinality),
 	}
 }
 
@@ -0,0 +1,44 @@
+-- Writes the hash vectors in postgresql_hll_hash.csv.gz, which Test_PostgresHashes
+-- checks against the Hash functions.  Run it against a database where the hll
+-- extension can be created, and note the version of postgresql-hll that it ran
+-- with in the commit that updates the vectors:
+--
+--     psql -X -q -f postgresql_hll_hash.sql | gzip -n > postgresql_hll_hash.csv.gz
+--
+-- Negative seeds make the extension print a warning to stderr, which doesn't
+-- end up in the output.
+
+\set ON_ERROR_STOP on
+\pset format unaligned
+\pset fieldsep ','
+\pset footer off
+
+SET client_encoding = 'UTF8';
+SET bytea_output = 'hex';
+
+CREATE EXTENSION IF NOT EXISTS hll;
+
+WITH seeds(seed, seed_ord) AS (
+    SELECT * FROM unnest(ARRAY[0, 1, 42, -1, 2147483647]) WITH ORDINALITY
+), hashes(function, function_ord, input, input_ord, seed, seed_ord, hash) AS (
+    SELECT 'hll_hash_bigint', 1, v::text, o, seed, seed_ord, hll_hash_bigint(v, seed)::bigint
+    FROM seeds, unnest(ARRAY[0, 1, -1, 42, 123456789, 9223372036854775807, -9223372036854775808]::bigint[]) WITH ORDINALITY AS i(v, o)
+    UNION ALL
+    SELECT 'hll_hash_integer', 2, v::text, o, seed, seed_ord, hll_hash_integer(v, seed)::bigint
+    FROM seeds, unnest(ARRAY[0, 1, -1, 42, 2147483647, -2147483648]::integer[]) WITH ORDINALITY AS i(v, o)
+    UNION ALL
+    SELECT 'hll_hash_smallint', 3, v::text, o, seed, seed_ord, hll_hash_smallint(v, seed)::bigint
+    FROM seeds, unnest(ARRAY[0, 1, -1, 42, 32767, -32768]::smallint[]) WITH ORDINALITY AS i(v, o)
+    UNION ALL
+    SELECT 'hll_hash_boolean', 4, v::text, o, seed, seed_ord, hll_hash_boolean(v, seed)::bigint
+    FROM seeds, unnest(ARRAY[false, true]) WITH ORDINALITY AS i(v, o)
+    UNION ALL
+    SELECT 'hll_hash_text', 5, v, o, seed, seed_ord, hll_hash_text(v, seed)::bigint
+    FROM seeds, unnest(ARRAY['', 'hello', 'The quick brown fox jumps over the lazy dog.', 'ünïcödé', '0123456789abcdef']) WITH ORDINALITY AS i(v, o)
+    UNION ALL
+    SELECT 'hll_hash_bytea', 6, v::text, o, seed, seed_ord, hll_hash_bytea(v, seed)::bigint
+    FROM seeds, unnest(ARRAY['\x', '\x00', '\xdeadbeef', '\x68656c6c6f']::bytea[]) WITH ORDINALITY AS i(v, o)
+)
+SELECT function, input, seed, hash
+FROM hashes
+ORDER BY seed_ord, function_ord, input_ord;
@@ -0,0 +1,220 @@
+package hll
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errNoSettings is returned when marshaling a zero value Hll before Defaults
+// has been invoked.  It is returned in place of the panic that would otherwise
+// occur since the encoding interfaces are expected to report failures as
+// errors.
+var errNoSettings = errors.New("cannot marshal Hll without settings.  Use NewHll or install Defaults")
+
+// MarshalBinary implements encoding.BinaryMarshaler.  The result is the same as
+// ToBytes.
+func (h *Hll) MarshalBinary() ([]byte, error) {
+	if h.settings == nil && getDefaults() == nil {
+		return nil, errNoSettings
+	}
+	return h.ToBytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.  It replaces the
+// receiver with the Hll deserialized from the provided bytes as FromBytes
+// would, so the log2m, regwidth, and thresholds are taken from the header
+// regardless of the receiver's settings.  It may be used on a zero value
+// whether or not Defaults has been invoked.
+//
+// The Hasher and Estimator are not part of the serialized form.  They are
+// retained from the receiver's settings or, for a zero value, from the default
+// settings if any have been installed.
+func (h *Hll) UnmarshalBinary(data []byte) error {
+
+	decoded, err := FromBytes(data)
+	if err != nil {
+		return err
+	}
+
+	return h.replace(decoded)
+}
+
+// Decode deserializes the provided bytes into the receiver as UnmarshalBinary
+// would, but it reuses the receiver's storage rather than allocating new
+// storage when the serialized Hll has the same representation and number of
+// registers.  It is intended for loops that decode many Hlls one after another.
+//
+// Since the storage is modified in place, copies of the receiver that share its
+// storage are modified as well.  If an error is returned, the receiver is left
+// empty with its previous settings.
+func (h *Hll) Decode(bytes []byte) error {
+
+	storageType, settings, err := parseHeader(bytes)
+	if err == nil {
+		settings, err = h.inheritSettings(settings)
+	}
+	if err == nil && storageType == dense && len(bytes)-3 != denseSizeInBytes(settings) {
+		err = ErrInsufficientBytes
+	}
+	if err != nil {
+		h.storage = nil
+		return err
+	}
+
+	previous := h.settings
+	h.storage = h.reusableStorage(storageType, settings)
+	h.settings = settings
+
+	if h.storage == nil {
+		switch storageType {
+		case explicit:
+			h.storage = make(explicitStorage)
+		case sparse:
+			h.storage = make(sparseStorage)
+		case dense:
+			h.storage = newDenseStorage(settings)
+		}
+	}
+
+	if h.storage != nil {
+		if err := h.storage.fromBytes(settings, bytes[3:]); err != nil {
+			h.storage = nil
+			h.settings = previous
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reusableStorage returns the receiver's storage, cleared, if it can be used to
+// decode a serialized Hll with the provided storage type and settings.
+// Otherwise, it returns nil.
+func (h *Hll) reusableStorage(storageType storageType, settings *settings) storage {
+
+	// NOTE : h.storage is returned rather than s since converting a slice to
+	//        an interface allocates.
+	switch s := h.storage.(type) {
+	case explicitStorage:
+		if storageType == explicit {
+			for value := range s {
+				delete(s, value)
+			}
+			return h.storage
+		}
+	case sparseStorage:
+		if storageType == sparse {
+			for reg := range s {
+				delete(s, reg)
+			}
+			return h.storage
+		}
+	case denseStorage:
+		// every register is overwritten by fromBytes, so the storage doesn't
+		// need to be cleared.
+		if storageType == dense && h.settings.log2m == settings.log2m && h.settings.regwidth == settings.regwidth {
+			return h.storage
+		}
+	}
+
+	return nil
+}
+
+// replace overwrites the receiver with the decoded Hll, substituting the
+// Hasher and Estimator as described by inheritSettings.
+func (h *Hll) replace(decoded Hll) error {
+
+	settings, err := h.inheritSettings(decoded.settings)
+	if err != nil {
+		return err
+	}
+
+	decoded.settings = settings
+	*h = decoded
+	return nil
+}
+
+// inheritSettings returns the decoded settings with the Hasher and Estimator
+// replaced by those of the receiver's settings or, for a zero value, those of
+// the default settings.
+func (h *Hll) inheritSettings(decoded *settings) (*settings, error) {
+
+	base := h.settings
+	if base == nil {
+		base = getDefaults()
+	}
+
+	if base == nil || (base.hasher == nil && base.estimator == ClassicEstimator) {
+		return decoded, nil
+	}
+
+	external := decoded.toExternal()
+	external.Hasher = base.hasher
+	external.Estimator = base.estimator
+
+	return external.toInternal()
+}
+
+// MarshalText implements encoding.TextMarshaler.  The result is the serialized
+// Hll in the "\x" prefixed hex format that PostgreSQL uses to print hll and
+// bytea values, e.g. "\x128b7f...".
+//
+// Unlike MarshalBinary, it is declared on the value so that Hll fields are
+// encoded as text even when the containing struct is not addressable.
+func (h Hll) MarshalText() ([]byte, error) {
+	data, err := h.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	text := make([]byte, 2+hex.EncodedLen(len(data)))
+	text[0], text[1] = '\\', 'x'
+	hex.Encode(text[2:], data)
+
+	return text, nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.  It accepts hex with or
+// without the "\x" prefix and otherwise behaves as UnmarshalBinary.
+func (h *Hll) UnmarshalText(text []byte) error {
+
+	if len(text) >= 2 && text[0] == '\\' && text[1] == 'x' {
+		text = text[2:]
+	}
+
+	data := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(data, text); err != nil {
+		return fmt.Errorf("invalid Hll hex: %v", err)
+	}
+
+	return h.UnmarshalBinary(data)
+}
+
+// MarshalJSON implements json.Marshaler.  The Hll is encoded as a JSON string
+// holding the text format produced by MarshalText.
+func (h Hll) MarshalJSON() ([]byte, error) {
+	text, err := h.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.  It accepts a JSON string in
+// either of the formats accepted by UnmarshalText.  Per the convention of the
+// encoding/json package, a JSON null leaves the receiver unchanged.
+func (h *Hll) UnmarshalJSON(data []byte) error {
+
+	if string(data) == "null" {
+		return nil
+	}
+
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("Hll must be encoded as a JSON string: %v", err)
+	}
+
+	return h.UnmarshalText([]byte(text))
+}
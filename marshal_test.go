@@ -0,0 +1,299 @@
+package hll
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = &Hll{}
+	_ encoding.BinaryUnmarshaler = &Hll{}
+	_ encoding.TextMarshaler     = Hll{}
+	_ encoding.TextUnmarshaler   = &Hll{}
+	_ json.Marshaler             = Hll{}
+	_ json.Unmarshaler           = &Hll{}
+)
+
+func Test_MarshalBinary(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, size := range []int{0, 10, 100, 10000} {
+		h := newHll(t, settings)
+		for i := 0; i < size; i++ {
+			h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+		}
+
+		data, err := h.MarshalBinary()
+		require.NoError(t, err)
+		assert.Equal(t, h.ToBytes(), data)
+
+		var decoded Hll
+		require.NoError(t, decoded.UnmarshalBinary(data))
+		assert.Equal(t, settings, decoded.Settings())
+		assert.Equal(t, h.ToBytes(), decoded.ToBytes())
+	}
+}
+
+func Test_MarshalBinary_ZeroValue(t *testing.T) {
+
+	resetDefaults()
+
+	_, err := (&Hll{}).MarshalBinary()
+	assert.Equal(t, errNoSettings, err)
+
+	// the header settings are used even though no defaults are installed.
+	h := newHll(t, Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	var decoded Hll
+	require.NoError(t, decoded.UnmarshalBinary(h.ToBytes()))
+	assert.Equal(t, h.Settings(), decoded.Settings())
+	assert.Equal(t, uint64(1), decoded.Cardinality())
+
+	defaults := Settings{Log2m: 12, Regwidth: 6, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true, Estimator: ImprovedEstimator}
+	require.NoError(t, Defaults(defaults))
+	defer resetDefaults()
+
+	data, err := (&Hll{}).MarshalBinary()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x11, 0xac, 0x7f}, data)
+
+	// the header wins over the defaults, but the estimator is retained.
+	decoded = Hll{}
+	require.NoError(t, decoded.UnmarshalBinary(h.ToBytes()))
+	assert.Equal(t, 11, decoded.Settings().Log2m)
+	assert.Equal(t, ImprovedEstimator, decoded.Settings().Estimator)
+}
+
+func Test_UnmarshalBinary_RetainsHasher(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true, Hasher: fnvHasher{}}
+	h := newHll(t, settings)
+	h.AddString("hello")
+
+	decoded := newHll(t, settings)
+	require.NoError(t, decoded.UnmarshalBinary(h.ToBytes()))
+	assert.Equal(t, settings, decoded.Settings())
+
+	// adding the same value again should not change the result.
+	decoded.AddString("hello")
+	assert.Equal(t, h.ToBytes(), decoded.ToBytes())
+}
+
+func Test_UnmarshalBinary_Errors(t *testing.T) {
+
+	h := newHll(t, Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+	h.AddRaw(0x9e3779b97f4a7c15)
+	original := h.ToBytes()
+
+	assert.Equal(t, ErrInsufficientBytes, h.UnmarshalBinary([]byte{0x11}))
+	assert.Error(t, h.UnmarshalBinary([]byte{0x21, 0x8b, 0x7f}))
+	assert.Equal(t, original, h.ToBytes())
+}
+
+func Test_Gob(t *testing.T) {
+
+	type record struct {
+		Name string
+		Hll  Hll
+	}
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	in := record{Name: "users", Hll: newHll(t, settings)}
+	for i := 0; i < 1000; i++ {
+		in.Hll.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(&in))
+
+	var out record
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&out))
+	assert.Equal(t, in.Name, out.Name)
+	assert.Equal(t, in.Hll.ToBytes(), out.Hll.ToBytes())
+}
+
+func Test_MarshalText(t *testing.T) {
+
+	h := newHll(t, Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+
+	text, err := h.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, `\x118b7f`, string(text))
+
+	h.AddRaw(0x9e3779b97f4a7c15)
+	text, err = h.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, `\x128b7f9e3779b97f4a7c15`, string(text))
+
+	for _, input := range []string{`\x128b7f9e3779b97f4a7c15`, `128b7f9e3779b97f4a7c15`, `128B7F9E3779B97F4A7C15`} {
+		var decoded Hll
+		require.NoError(t, decoded.UnmarshalText([]byte(input)), input)
+		assert.Equal(t, h.ToBytes(), decoded.ToBytes(), input)
+	}
+}
+
+func Test_UnmarshalText_Errors(t *testing.T) {
+
+	var h Hll
+	assert.Error(t, h.UnmarshalText([]byte(`\x128b7`)))
+	assert.Error(t, h.UnmarshalText([]byte(`\x128b7g`)))
+	assert.Error(t, h.UnmarshalText([]byte(`\\x128b7f`)))
+	assert.Equal(t, ErrInsufficientBytes, h.UnmarshalText([]byte(`\x`)))
+}
+
+func Test_MarshalJSON(t *testing.T) {
+
+	type record struct {
+		Name string `json:"name"`
+		Hll  Hll    `json:"hll"`
+	}
+
+	h := newHll(t, Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	// the record is passed by value, so the Hll field is not addressable.
+	data, err := json.Marshal(record{Name: "users", Hll: h})
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"users","hll":"\\x128b7f9e3779b97f4a7c15"}`, string(data))
+
+	var out record
+	require.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, h.ToBytes(), out.Hll.ToBytes())
+
+	out = record{}
+	require.NoError(t, json.Unmarshal([]byte(`{"hll":"128b7f9e3779b97f4a7c15"}`), &out))
+	assert.Equal(t, h.ToBytes(), out.Hll.ToBytes())
+
+	// null leaves the value untouched.
+	require.NoError(t, json.Unmarshal([]byte(`{"hll":null}`), &out))
+	assert.Equal(t, h.ToBytes(), out.Hll.ToBytes())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"hll":1234}`), &out))
+	assert.Error(t, json.Unmarshal([]byte(`{"hll":"\\xzz"}`), &out))
+}
+
+func Test_Decode(t *testing.T) {
+
+	var inputs [][]byte
+	for _, settings := range streamSettings {
+		for _, size := range []int{0, 1, 10, 100, 1000, 10000} {
+			h := newHll(t, settings)
+			for i := 0; i < size; i++ {
+				h.AddRaw(uint64(i+size) * 0x9e3779b97f4a7c15)
+			}
+			inputs = append(inputs, h.ToBytes())
+		}
+	}
+
+	// decode every input after every other input so that each storage type is
+	// reused as well as replaced.
+	for _, first := range inputs {
+		for _, second := range inputs {
+			var h Hll
+			require.NoError(t, h.Decode(first))
+			require.NoError(t, h.Decode(second))
+
+			expected, err := FromBytes(second)
+			require.NoError(t, err)
+			assert.Equal(t, expected.Settings(), h.Settings())
+			assert.Equal(t, reflect.TypeOf(expected.storage), reflect.TypeOf(h.storage))
+			assert.Equal(t, second, h.ToBytes())
+		}
+	}
+}
+
+func Test_Decode_ReusesStorage(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, size := range []int{10, 100, 1000} {
+		a, b := newHll(t, settings), newHll(t, settings)
+		for i := 0; i < size; i++ {
+			a.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+			b.AddRaw(uint64(i+size) * 0x9e3779b97f4a7c15)
+		}
+		aBytes, bBytes := a.ToBytes(), b.ToBytes()
+
+		var h Hll
+		require.NoError(t, h.Decode(aBytes))
+		storage := reflect.ValueOf(h.storage).Pointer()
+
+		require.NoError(t, h.Decode(bBytes))
+		assert.Equal(t, storage, reflect.ValueOf(h.storage).Pointer())
+		assert.Equal(t, bBytes, h.ToBytes())
+	}
+
+	dense := newHll(t, Settings{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	dense.AddRaw(0x9e3779b97f4a7c15)
+	denseBytes := dense.ToBytes()
+
+	var h Hll
+	require.NoError(t, h.Decode(denseBytes))
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = h.Decode(denseBytes)
+	})
+	assert.Zero(t, allocs)
+}
+
+func Test_Decode_Errors(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true, Estimator: ImprovedEstimator}
+
+	h := newHll(t, settings)
+	for i := 0; i < 1000; i++ {
+		h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+	}
+	data := h.ToBytes()
+
+	assert.Equal(t, ErrInsufficientBytes, h.Decode(data[:len(data)-1]))
+	assertEmpty(t, h)
+	assert.Equal(t, settings, h.Settings())
+
+	require.NoError(t, h.Decode(data))
+	assert.Error(t, h.Decode([]byte{0x21, 0x8b, 0x7f}))
+	assertEmpty(t, h)
+	assert.Equal(t, settings, h.Settings())
+
+	// a valid header with different settings followed by invalid data.
+	require.NoError(t, h.Decode(data))
+	assert.Equal(t, ErrInsufficientBytes, h.Decode([]byte{0x12, 0x8a, 0x7f, 0, 0, 0, 0, 0, 0, 1}))
+	assertEmpty(t, h)
+	assert.Equal(t, settings, h.Settings())
+}
+
+func BenchmarkDecode(b *testing.B) {
+	h, _ := NewHll(Settings{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	h.AddRaw(0x9e3779b97f4a7c15)
+	data := h.ToBytes()
+
+	var decoded Hll
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = decoded.Decode(data)
+	}
+}
+
+func BenchmarkFromBytes(b *testing.B) {
+	h, _ := NewHll(Settings{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	h.AddRaw(0x9e3779b97f4a7c15)
+	data := h.ToBytes()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = FromBytes(data)
+	}
+}
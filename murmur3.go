@@ -0,0 +1,152 @@
+package hll
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// the MurmurHash3 x64_128 constants.
+const (
+	murmur3C1 = 0x87c37b91114253d5
+	murmur3C2 = 0x4cf5ad432745937f
+)
+
+// Murmur3Hasher is the default Hasher.  It computes the first 64 bits of the
+// MurmurHash3 x64_128 hash with a seed of 0.
+var Murmur3Hasher Hasher = murmur3Hasher{}
+
+type murmur3Hasher struct{}
+
+func (murmur3Hasher) Sum64(data []byte) uint64 {
+	return murmur3Sum64(data)
+}
+
+// murmur3Sum64 returns the first 64 bits of the MurmurHash3 x64_128 hash of the
+// data using a seed of 0.  This is the hash recommended for use with the AK
+// storage spec and is equivalent to murmur3.Sum64 in
+// https://github.com/spaolacci/murmur3.
+func murmur3Sum64(data []byte) uint64 {
+	h1, _ := murmur3Sum128(data, 0)
+	return h1
+}
+
+// murmur3Sum128 is a port of MurmurHash3_x64_128 from the reference
+// implementation at https://github.com/aappleby/smhasher.  It returns the two
+// halves of the 128 bit hash.
+func murmur3Sum128(data []byte, seed uint32) (uint64, uint64) {
+
+	h1, h2 := uint64(seed), uint64(seed)
+	length := len(data)
+
+	// body...process 16 byte blocks.
+	nBlocks := length / 16
+	for i := 0; i < nBlocks; i++ {
+		block := data[i*16 : i*16+16]
+		k1 := binary.LittleEndian.Uint64(block[0:8])
+		k2 := binary.LittleEndian.Uint64(block[8:16])
+
+		k1 *= murmur3C1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2
+		h1 ^= k1
+
+		h1 = bits.RotateLeft64(h1, 27)
+		h1 += h2
+		h1 = h1*5 + 0x52dce729
+
+		k2 *= murmur3C2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1
+		h2 ^= k2
+
+		h2 = bits.RotateLeft64(h2, 31)
+		h2 += h1
+		h2 = h2*5 + 0x38495ab5
+	}
+
+	// tail...process the remaining 0-15 bytes.  the fallthrough cascade
+	// mirrors the switch in the reference implementation.
+	tail := data[nBlocks*16:]
+	var k1, k2 uint64
+
+	switch len(tail) {
+	case 15:
+		k2 ^= uint64(tail[14]) << 48
+		fallthrough
+	case 14:
+		k2 ^= uint64(tail[13]) << 40
+		fallthrough
+	case 13:
+		k2 ^= uint64(tail[12]) << 32
+		fallthrough
+	case 12:
+		k2 ^= uint64(tail[11]) << 24
+		fallthrough
+	case 11:
+		k2 ^= uint64(tail[10]) << 16
+		fallthrough
+	case 10:
+		k2 ^= uint64(tail[9]) << 8
+		fallthrough
+	case 9:
+		k2 ^= uint64(tail[8])
+		k2 *= murmur3C2
+		k2 = bits.RotateLeft64(k2, 33)
+		k2 *= murmur3C1
+		h2 ^= k2
+		fallthrough
+	case 8:
+		k1 ^= uint64(tail[7]) << 56
+		fallthrough
+	case 7:
+		k1 ^= uint64(tail[6]) << 48
+		fallthrough
+	case 6:
+		k1 ^= uint64(tail[5]) << 40
+		fallthrough
+	case 5:
+		k1 ^= uint64(tail[4]) << 32
+		fallthrough
+	case 4:
+		k1 ^= uint64(tail[3]) << 24
+		fallthrough
+	case 3:
+		k1 ^= uint64(tail[2]) << 16
+		fallthrough
+	case 2:
+		k1 ^= uint64(tail[1]) << 8
+		fallthrough
+	case 1:
+		k1 ^= uint64(tail[0])
+		k1 *= murmur3C1
+		k1 = bits.RotateLeft64(k1, 31)
+		k1 *= murmur3C2
+		h1 ^= k1
+	}
+
+	// finalization
+	h1 ^= uint64(length)
+	h2 ^= uint64(length)
+
+	h1 += h2
+	h2 += h1
+
+	h1 = murmur3Fmix64(h1)
+	h2 = murmur3Fmix64(h2)
+
+	h1 += h2
+	h2 += h1
+
+	return h1, h2
+}
+
+// murmur3Fmix64 is the 64 bit finalization mix that forces all bits of a hash
+// block to avalanche.
+func murmur3Fmix64(k uint64) uint64 {
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return k
+}
@@ -0,0 +1,48 @@
+package hll
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test_murmur3Sum128 verifies the port against vectors produced by the
+// reference implementation.
+func Test_murmur3Sum128(t *testing.T) {
+
+	tests := []struct {
+		seed   uint32
+		input  string
+		h1, h2 uint64
+	}{
+		{0x00, "", 0x0000000000000000, 0x0000000000000000},
+		{0x00, "hello", 0xcbd8a7b341bd9b02, 0x5b1e906a48ae1d19},
+		{0x00, "hello, world", 0x342fac623a5ebc8e, 0x4cdcbc079642414d},
+		{0x00, "19 Jan 2038 at 3:14:07 AM", 0xb89e5988b737affc, 0x664fc2950231b2cb},
+		{0x00, "The quick brown fox jumps over the lazy dog.", 0xcd99481f9ee902c9, 0x695da1a38987b6e7},
+		{0x01, "", 0x4610abe56eff5cb5, 0x51622daa78f83583},
+		{0x01, "hello", 0xa78ddff5adae8d10, 0x128900ef20900135},
+		{0x2a, "", 0xf02aa77dfa1b8523, 0xd1016610da11cbb9},
+		{0x2a, "hello", 0xc4b8b3c960af6f08, 0x2334b875b0efbc7a},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%q", tt.seed, tt.input), func(t *testing.T) {
+			h1, h2 := murmur3Sum128([]byte(tt.input), tt.seed)
+			assert.Equal(t, tt.h1, h1, "h1")
+			assert.Equal(t, tt.h2, h2, "h2")
+			if tt.seed == 0 {
+				assert.Equal(t, tt.h1, murmur3Sum64([]byte(tt.input)))
+			}
+		})
+	}
+}
+
+func BenchmarkMurmur3Sum64(b *testing.B) {
+	data := []byte("The quick brown fox jumps over the lazy dog.")
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		murmur3Sum64(data)
+	}
+}
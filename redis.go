@@ -0,0 +1,263 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Redis stores a HyperLogLog as the string value of a key, so it can be read
+// with GET and written with SET like any other string.  The format is described
+// in https://github.com/redis/redis/blob/unstable/src/hyperloglog.c.
+//
+// A Redis HyperLogLog has 16384 registers of 6 bits and uses the same scheme as
+// the storage spec to split a hash into a register index (the low 14 bits) and
+// value (one plus the number of trailing zeros of the remaining bits).  The
+// only difference is that Redis sets the register to 51 for a hash whose upper
+// 50 bits are zero, whereas the storage spec ignores it.  Since that happens
+// with probability 2^-50, an Hll with RedisSettings populated by AddBytes or
+// AddString has the same registers as a key populated by PFADD.
+
+const (
+	redisLog2m        = 14
+	redisRegisters    = 1 << redisLog2m
+	redisRegwidth     = 6
+	redisHeaderSize   = 16
+	redisDenseSize    = redisRegisters * redisRegwidth / 8
+	redisInvalidCache = 0x80 // set in the final byte of the cached cardinality.
+
+	redisDense  = 0
+	redisSparse = 1
+
+	// redisSparseMaxBytes is the default value of hll-sparse-max-bytes.  Redis
+	// promotes keys beyond this size to the dense encoding.
+	redisSparseMaxBytes = 3000
+
+	// limits of the sparse opcodes.
+	redisZeroMaxLen = 64
+	redisValMaxLen  = 4
+	redisValMax     = 32
+
+	// redisSeed is the seed that Redis uses with MurmurHash64A.
+	redisSeed = 0xadc83b19
+)
+
+// RedisHasher is a Hasher that computes the same hash as Redis's PFADD, which
+// is MurmurHash64A with a seed of 0xadc83b19.
+var RedisHasher Hasher = redisHasher{}
+
+// RedisSettings are the settings of the Hlls returned by FromRedis.  They have
+// the same number and width of registers as Redis and hash values with
+// RedisHasher so that AddBytes and AddString match PFADD.  Since the Hasher
+// differs from the default, StrictUnion refuses to combine these Hlls with
+// ones populated using Murmur3Hasher, as the registers would be meaningless.
+var RedisSettings = Settings{
+	Log2m:             redisLog2m,
+	Regwidth:          redisRegwidth,
+	ExplicitThreshold: AutoExplicitThreshold,
+	SparseEnabled:     true,
+	Hasher:            RedisHasher,
+}
+
+// redisMagic is the first 4 bytes of every Redis HyperLogLog.
+var redisMagic = [4]byte{'H', 'Y', 'L', 'L'}
+
+// errRedisInvalid is returned by FromRedis when the sparse opcodes do not cover
+// every register exactly once.
+var errRedisInvalid = errors.New("invalid Redis HyperLogLog sparse encoding")
+
+type redisHasher struct{}
+
+func (redisHasher) Sum64(data []byte) uint64 {
+	return murmurHash64A(data, redisSeed)
+}
+
+// FromRedis converts the string value of a Redis HyperLogLog key in either the
+// sparse or dense encoding to an Hll with RedisSettings.  The cached
+// cardinality in the header is ignored.
+func FromRedis(data []byte) (Hll, error) {
+
+	if len(data) < redisHeaderSize {
+		return Hll{}, ErrInsufficientBytes
+	}
+	if [4]byte{data[0], data[1], data[2], data[3]} != redisMagic {
+		return Hll{}, errors.New("not a Redis HyperLogLog: missing HYLL magic")
+	}
+
+	settings, err := RedisSettings.toInternal()
+	if err != nil {
+		return Hll{}, err
+	}
+
+	h := Hll{settings: settings}
+
+	payload := data[redisHeaderSize:]
+
+	switch data[4] {
+	case redisDense:
+		if len(payload) != redisDenseSize {
+			return Hll{}, ErrInsufficientBytes
+		}
+		for regnum := 0; regnum < redisRegisters; regnum++ {
+			h.setRegister(regnum, getLSB6(payload, regnum))
+		}
+	case redisSparse:
+		regnum := 0
+		for i := 0; i < len(payload); i++ {
+			var value byte
+			var runLength int
+
+			switch op := payload[i]; {
+			case op&0xc0 == 0x00: // ZERO: 00xxxxxx
+				runLength = int(op&0x3f) + 1
+			case op&0xc0 == 0x40: // XZERO: 01xxxxxx yyyyyyyy
+				if i+1 >= len(payload) {
+					return Hll{}, ErrInsufficientBytes
+				}
+				i++
+				runLength = (int(op&0x3f)<<8 | int(payload[i])) + 1
+			default: // VAL: 1vvvvvxx
+				value = (op>>2)&0x1f + 1
+				runLength = int(op&0x3) + 1
+			}
+
+			if regnum+runLength > redisRegisters {
+				return Hll{}, errRedisInvalid
+			}
+			for end := regnum + runLength; regnum < end; regnum++ {
+				h.setRegister(regnum, value)
+			}
+		}
+		if regnum != redisRegisters {
+			return Hll{}, errRedisInvalid
+		}
+	default:
+		return Hll{}, fmt.Errorf("invalid Redis HyperLogLog encoding: %d", data[4])
+	}
+
+	if h.storage != nil && h.storage.overCapacity(settings) {
+		h.upgrade()
+	}
+
+	return h, nil
+}
+
+// ToRedis converts the Hll to the string value of a Redis HyperLogLog key,
+// which can be written with SET and then used with PFADD, PFCOUNT, and
+// PFMERGE.  The sparse encoding is used when every register value is at most
+// 32 and the result is no larger than the default hll-sparse-max-bytes of 3000.
+// Otherwise, the dense encoding is used.  Redis accepts any valid encoding, so
+// the result has the same registers as the value PFADD would produce but not
+// necessarily the same bytes.
+// The cached cardinality is marked invalid so that Redis recomputes it.
+//
+// Hlls with a log2m larger than 14 are folded as by Reduce, and register
+// values that do not fit in 6 bits are clamped.  An error is returned if log2m
+// is smaller than 14.
+func (h *Hll) ToRedis() ([]byte, error) {
+
+	h.initOrPanic()
+
+	if h.settings.log2m < redisLog2m {
+		return nil, fmt.Errorf("cannot convert Log2m %d to Redis HyperLogLog.  Requires at least %d", h.settings.log2m, redisLog2m)
+	}
+
+	registers, empty := h.convertedRegisters(redisLog2m, redisRegwidth)
+
+	data := make([]byte, redisHeaderSize, redisHeaderSize+redisDenseSize)
+	copy(data, redisMagic[:])
+
+	// a new key has a valid cached cardinality of 0, and PFADD invalidates it
+	// when a register changes.
+	if !empty {
+		data[15] = redisInvalidCache
+	}
+
+	if sparse, ok := redisSparseEncode(registers); ok && redisHeaderSize+len(sparse) <= redisSparseMaxBytes {
+		data[4] = redisSparse
+		return append(data, sparse...), nil
+	}
+
+	data[4] = redisDense
+	data = data[:redisHeaderSize+redisDenseSize]
+	for regnum, value := range registers {
+		setLSB6(data[redisHeaderSize:], regnum, value)
+	}
+
+	return data, nil
+}
+
+// redisSparseEncode encodes the registers with the sparse opcodes: each run of
+// zeros is a single ZERO or XZERO opcode, and each run of equal values is split
+// greedily into VAL opcodes of up to 4 registers.  PFADD may split a run
+// differently, e.g. into VAL(v,3) VAL(v,2) rather than VAL(v,4) VAL(v,1), but
+// both are valid encodings of the same registers.
+// It returns false if a register value is too large for the sparse encoding.
+func redisSparseEncode(registers []byte) ([]byte, bool) {
+
+	var sparse []byte
+
+	for regnum := 0; regnum < len(registers); {
+		value := registers[regnum]
+
+		runLength := 1
+		for regnum+runLength < len(registers) && registers[regnum+runLength] == value {
+			runLength++
+		}
+
+		if value == 0 {
+			if runLength > redisZeroMaxLen {
+				sparse = append(sparse, 0x40|byte((runLength-1)>>8), byte(runLength-1))
+			} else {
+				sparse = append(sparse, byte(runLength-1))
+			}
+			regnum += runLength
+			continue
+		}
+
+		if value > redisValMax {
+			return nil, false
+		}
+
+		if runLength > redisValMaxLen {
+			runLength = redisValMaxLen
+		}
+		sparse = append(sparse, 0x80|(value-1)<<2|byte(runLength-1))
+		regnum += runLength
+	}
+
+	return sparse, true
+}
+
+// murmurHash64A is Austin Appleby's MurmurHash64A as used by Redis.  Blocks are
+// read as little endian words regardless of the platform.
+func murmurHash64A(data []byte, seed uint64) uint64 {
+
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+
+	h := seed ^ (uint64(len(data)) * m)
+
+	for ; len(data) >= 8; data = data[8:] {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+
+		h ^= k
+		h *= m
+	}
+
+	if len(data) > 0 {
+		for i := len(data) - 1; i >= 0; i-- {
+			h ^= uint64(data[i]) << (8 * uint(i))
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+
+	return h
+}
@@ -0,0 +1,196 @@
+package hll
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisHeader returns the header of a Redis HyperLogLog with the encoding and
+// an invalid cached cardinality.
+func redisHeader(encoding byte) []byte {
+	return []byte{'H', 'Y', 'L', 'L', encoding, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x80}
+}
+
+//go:generate go run gen_redis.go
+
+// Test_RedisPFADD runs through the vectors in testdata/redis/pfadd.csv.gz.
+// Each line contains space separated elements and the hex of the key's value
+// after adding them with a single PFADD to a new key.  The values are
+// generated by gen_redis.go, a port of PFADD from src/hyperloglog.c in Redis
+// 7.0, which builds the sparse encoding by splitting and merging opcodes
+// rather than from the registers.  Its opcodes can differ from those of
+// ToRedis, so the values are compared by their registers.
+func Test_RedisPFADD(t *testing.T) {
+
+	readCSV(t, "testdata/redis/pfadd.csv.gz", func(parts []string, lineNo int) {
+
+		require.Equal(t, 2, len(parts), "required 2 columns at line %d", lineNo)
+		require.True(t, strings.HasPrefix(parts[1], `\x`), "missing \\x at line %d", lineNo)
+
+		expected, err := hex.DecodeString(parts[1][2:])
+		require.NoError(t, err, "invalid hex at line %d", lineNo)
+
+		h := newHll(t, RedisSettings)
+		for _, element := range strings.Fields(parts[0]) {
+			h.AddString(element)
+		}
+
+		decoded, err := FromRedis(expected)
+		require.NoError(t, err, "failed to convert line %d", lineNo)
+
+		// h may still be explicit, so compare it after promotion.
+		promoted := newHll(t, Settings{Log2m: 14, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+		promoted.Union(h)
+		require.Equal(t, registerValues(promoted), registerValues(decoded), "incorrect registers at line %d", lineNo)
+
+		actual, err := h.ToRedis()
+		require.NoError(t, err)
+
+		converted, err := FromRedis(actual)
+		require.NoError(t, err, "failed to convert value at line %d", lineNo)
+		require.Equal(t, registerValues(decoded), registerValues(converted), "incorrect value at line %d", lineNo)
+	})
+}
+
+func Test_RedisHasher(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected uint64
+	}{
+		{"", 15627466953755236146},
+		{"a", 6039968161137406375},
+		{"hello", 1109414937308947456},
+		{"12345678", 10802930868819274067},
+		{"123456789abcdef", 732220148985825209},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, RedisHasher.Sum64([]byte(tt.input)), "incorrect hash of %q", tt.input)
+	}
+}
+
+func Test_FromRedis(t *testing.T) {
+
+	// sparse: 100 zeros, two 3s, a zero, a 32, and zeros for the rest.
+	sparse := append(redisHeader(redisSparse), 0x40, 0x63, 0x89, 0x00, 0xfc, 0x7f, 0x97)
+
+	h, err := FromRedis(sparse)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{100: 3, 101: 3, 103: 32}, h.storage)
+
+	actual, err := h.ToRedis()
+	require.NoError(t, err)
+	assert.Equal(t, sparse, actual)
+
+	// dense: registers are packed from the least significant bit of each byte.
+	dense := append(redisHeader(redisDense), make([]byte, redisDenseSize)...)
+	dense[16], dense[17] = 0xc1, 0x0f // register 0 is 1 and register 1 is 63.
+	dense[len(dense)-1] = 0x14        // register 16383 is 5.
+
+	h, err = FromRedis(dense)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{0: 1, 1: 63, 16383: 5}, h.storage)
+
+	// 63 can't be represented with the sparse encoding.
+	actual, err = h.ToRedis()
+	require.NoError(t, err)
+	assert.Equal(t, dense, actual)
+
+	// the cached cardinality is ignored.
+	cached := append([]byte(nil), sparse...)
+	copy(cached[8:], []byte{42, 0, 0, 0, 0, 0, 0, 0})
+	h, err = FromRedis(cached)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{100: 3, 101: 3, 103: 32}, h.storage)
+}
+
+func Test_ToRedis(t *testing.T) {
+
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = RedisHasher.Sum64([]byte{byte(i), byte(i >> 8)})
+	}
+
+	expected := newHll(t, RedisSettings)
+	for _, value := range values {
+		expected.AddRaw(value)
+	}
+	expectedBytes, err := expected.ToRedis()
+	require.NoError(t, err)
+
+	// the registers are converted regardless of the storage and larger Hlls are
+	// folded down to 16384 registers.  none of these hashes has a value too
+	// large for 5 bit registers.
+	for _, settings := range []Settings{
+		{Log2m: 14, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false},
+		{Log2m: 14, Regwidth: 8, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+		{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true},
+		{Log2m: 17, Regwidth: 6, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: false},
+	} {
+		h := newHll(t, settings)
+		for _, value := range values {
+			h.AddRaw(value)
+		}
+
+		actual, err := h.ToRedis()
+		require.NoError(t, err)
+		assert.Equal(t, expectedBytes, actual, "%+v", settings)
+	}
+
+	// register values that don't fit in 6 bits are clamped.
+	wide := newHll(t, Settings{Log2m: 14, Regwidth: 8, ExplicitThreshold: 0, SparseEnabled: true})
+	wide.storage = sparseStorage{7: 100}
+	actual, err := wide.ToRedis()
+	require.NoError(t, err)
+	decoded, err := FromRedis(actual)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{7: 63}, decoded.storage)
+
+	// an empty Hll has a valid cached cardinality of 0 like a new key.
+	empty := newHll(t, RedisSettings)
+	actual, err = empty.ToRedis()
+	require.NoError(t, err)
+	assert.Equal(t, []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"), actual)
+
+	small := newHll(t, Settings{Log2m: 13, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	_, err = small.ToRedis()
+	assert.Error(t, err)
+}
+
+func Test_FromRedis_Errors(t *testing.T) {
+
+	empty := []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff")
+	dense := append(redisHeader(redisDense), make([]byte, redisDenseSize)...)
+
+	tests := []struct {
+		label string
+		bytes []byte
+		err   error
+	}{
+		{"short header", empty[:15], ErrInsufficientBytes},
+		{"dense truncated", dense[:len(dense)-1], ErrInsufficientBytes},
+		{"dense trailing", append(dense, 0), ErrInsufficientBytes},
+		{"xzero truncated", empty[:17], ErrInsufficientBytes},
+		{"sparse incomplete", append(redisHeader(redisSparse), 0x7f, 0xfe), errRedisInvalid},
+		{"sparse overflow", append(append([]byte(nil), empty...), 0x00), errRedisInvalid},
+		{"sparse no opcodes", redisHeader(redisSparse), errRedisInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			_, err := FromRedis(tt.bytes)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	_, err := FromRedis([]byte("HYLX\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x7f\xff"))
+	assert.Error(t, err)
+
+	_, err = FromRedis(append(redisHeader(2), empty[16:]...))
+	assert.Error(t, err)
+}
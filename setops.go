@@ -0,0 +1,412 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+)
+
+// maxIntersectionHlls is the maximum number of Hlls that may be passed to
+// IntersectionCardinalityAll.  Inclusion-exclusion requires a union for every
+// non-empty subset of the Hlls, and the error of the result grows with the
+// number of terms.
+const maxIntersectionHlls = 16
+
+// unionAllChunkRegisters is the minimum number of registers that each goroutine
+// processes at a time when UnionAll merges dense storage in parallel.
+const unionAllChunkRegisters = 1 << 14
+
+// unionAllParallelRegisters is the number of registers that must be merged
+// before UnionAll will merge dense storage in parallel.
+const unionAllParallelRegisters = 1 << 20
+
+// UnionAll returns the union of all of the provided Hlls without modifying any
+// of them.  It returns ErrIncompatible if the Hlls don't all have the same
+// regwidth, log2m, and hasher settings.  The result has the settings of the
+// first Hll, and it is the zero value if no Hlls are provided.
+//
+// The result is the same as calling StrictUnion repeatedly, but it is more
+// efficient for large numbers of Hlls.  The final representation is chosen up
+// front so that storage is only allocated and upgraded once, and dense storage
+// is merged in a single pass that is split across goroutines when there are
+// many registers to merge.
+func UnionAll(hlls ...Hll) (Hll, error) {
+
+	if err := checkCompatible(hlls); err != nil {
+		return Hll{}, err
+	}
+
+	if len(hlls) == 0 {
+		return Hll{}, nil
+	}
+
+	settings := hlls[0].settings
+	union := Hll{settings: settings}
+
+	var explicits []explicitStorage
+	var sparses []sparseStorage
+	var denses []denseStorage
+	for _, hll := range hlls {
+		switch s := hll.storage.(type) {
+		case explicitStorage:
+			explicits = append(explicits, s)
+		case sparseStorage:
+			sparses = append(sparses, s)
+		case denseStorage:
+			denses = append(denses, s)
+		}
+	}
+
+	// only explicit values...the result is explicit unless it's over capacity.
+	if len(sparses) == 0 && len(denses) == 0 {
+		if len(explicits) == 0 {
+			return union, nil
+		}
+
+		explicit := make(explicitStorage)
+		for _, s := range explicits {
+			for value := range s {
+				explicit[value] = struct{}{}
+			}
+		}
+
+		if !explicit.overCapacity(settings) {
+			union.storage = explicit
+			return union, nil
+		}
+
+		explicits = []explicitStorage{explicit}
+	}
+
+	// no dense storage...merge into sparse storage and keep it unless it's over
+	// capacity.
+	var dense denseStorage
+	if len(denses) == 0 && settings.sparseEnabled {
+		sparse := make(sparseStorage)
+		for _, s := range sparses {
+			for regnum, value := range s {
+				sparse.setIfGreater(settings, int(regnum), value)
+			}
+		}
+		addExplicits(settings, sparse, explicits)
+
+		if !sparse.overCapacity(settings) {
+			union.storage = sparse
+			return union, nil
+		}
+
+		dense = sparseToDense(settings, sparse)
+	} else {
+		dense = newDenseStorage(settings)
+		unionAllDense(settings, dense, denses)
+		for _, s := range sparses {
+			for regnum, value := range s {
+				dense.setIfGreater(settings, int(regnum), value)
+			}
+		}
+		addExplicits(settings, dense, explicits)
+	}
+
+	union.storage = dense
+	return union, nil
+}
+
+// addExplicits adds the values from the explicit storage to the registers.
+func addExplicits(settings *settings, s registers, explicits []explicitStorage) {
+	for _, explicit := range explicits {
+		for value := range explicit {
+			if regnum, pW := settings.register(value); pW > 0 {
+				s.setIfGreater(settings, regnum, pW)
+			}
+		}
+	}
+}
+
+// unionAllDense merges the inputs into dst.  Each chunk of registers is merged
+// from every input before moving on to the next chunk so that the chunk of dst
+// remains in cache.  If there are enough registers, the chunks are divided
+// among goroutines.
+func unionAllDense(settings *settings, dst denseStorage, inputs []denseStorage) {
+
+	numReg := 1 << uint(settings.log2m)
+	chunkSize := unionChunkSize(settings, unionAllChunkRegisters)
+	if chunkSize >= numReg {
+		for _, input := range inputs {
+			dst.union(settings, input)
+		}
+		return
+	}
+
+	numChunks := (numReg + chunkSize - 1) / chunkSize
+	mergeChunk := func(chunk int) {
+		start := chunk * chunkSize
+		n := chunkSize
+		if start+n > numReg {
+			n = numReg - start
+		}
+
+		word := start * settings.regwidth / 64
+		for _, input := range inputs {
+			dst[word:].unionRegisters(settings, input[word:], n)
+		}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > numChunks {
+		workers = numChunks
+	}
+	if workers < 2 || numReg*len(inputs) < unionAllParallelRegisters {
+		for chunk := 0; chunk < numChunks; chunk++ {
+			mergeChunk(chunk)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	chunks := make(chan int)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range chunks {
+				mergeChunk(chunk)
+			}
+		}()
+	}
+	for chunk := 0; chunk < numChunks; chunk++ {
+		chunks <- chunk
+	}
+	close(chunks)
+	wg.Wait()
+}
+
+// IntersectionCardinality estimates the number of values that have been added
+// to both of the provided Hlls.  It returns ErrIncompatible if the Hlls don't
+// have the same regwidth, log2m, and hasher settings.
+//
+// If both Hlls use the explicit representation, the result is exact.
+// Otherwise, it is computed by inclusion-exclusion, |A| + |B| - |A ∪ B|, using
+// the estimator in the settings of a.  The result is clamped to the smaller of
+// the two cardinalities.  Note that the error of the union is relative to the
+// size of the union, so the relative error of the intersection can be very
+// large when the intersection is small compared to the sets.
+func IntersectionCardinality(a, b Hll) (uint64, error) {
+	return IntersectionCardinalityAll(a, b)
+}
+
+// IntersectionCardinalityAll estimates the number of values that have been
+// added to all of the provided Hlls.  It returns ErrIncompatible if the Hlls
+// don't all have the same regwidth, log2m, and hasher settings, and an error
+// if more than 16 Hlls are provided.
+//
+// As with IntersectionCardinality, the result is exact if all of the Hlls use
+// the explicit representation and is otherwise computed by inclusion-exclusion
+// over the unions of every non-empty subset of the Hlls.  The error compounds
+// with each additional Hll.
+func IntersectionCardinalityAll(hlls ...Hll) (uint64, error) {
+
+	if len(hlls) > maxIntersectionHlls {
+		return 0, fmt.Errorf("cannot intersect more than %d Hlls.  Got %d", maxIntersectionHlls, len(hlls))
+	}
+
+	if err := checkCompatible(hlls); err != nil {
+		return 0, err
+	}
+
+	if len(hlls) == 0 {
+		return 0, nil
+	}
+
+	// the intersection is bounded by the smallest set.
+	smallest := math.Inf(1)
+	allExplicit := true
+	for i := range hlls {
+		switch hlls[i].storage.(type) {
+		case nil:
+			// empty...the intersection is empty as well.
+			return 0, nil
+		case explicitStorage:
+		default:
+			allExplicit = false
+		}
+		smallest = math.Min(smallest, hlls[i].estimateCardinality(hlls[0].settings.estimator))
+	}
+
+	if allExplicit {
+		return explicitIntersection(hlls), nil
+	}
+
+	estimate := inclusionExclusion(hlls[0].settings.estimator, Hll{settings: hlls[0].settings}, hlls, 0, 0)
+	return uint64(math.Ceil(math.Max(0, math.Min(smallest, estimate)))), nil
+}
+
+// DifferenceCardinality estimates the number of values that have been added to
+// a but not to b.  It returns ErrIncompatible if the Hlls don't have the same
+// regwidth, log2m, and hasher settings.
+//
+// If both Hlls use the explicit representation, the result is exact.
+// Otherwise, it is computed as |A ∪ B| - |B| and clamped to the cardinality of
+// a, so the caveats about the error of IntersectionCardinality apply.
+func DifferenceCardinality(a, b Hll) (uint64, error) {
+
+	pair, err := estimatePair(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	difference := math.Max(0, math.Min(pair.a, pair.union-pair.b))
+	return uint64(math.Ceil(difference)), nil
+}
+
+// Jaccard estimates the Jaccard similarity of the provided Hlls, which is the
+// size of their intersection divided by the size of their union.  The result
+// is between 0 and 1, and it is 0 if both Hlls are empty.  It returns
+// ErrIncompatible if the Hlls don't have the same regwidth, log2m, and hasher
+// settings.
+//
+// The intersection is computed as described for IntersectionCardinality, so
+// the absolute error of the result is roughly the relative error of the union.
+func Jaccard(a, b Hll) (float64, error) {
+
+	pair, err := estimatePair(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	if pair.union == 0 {
+		return 0, nil
+	}
+	return math.Min(1, pair.intersection/pair.union), nil
+}
+
+// Overlap estimates the overlap coefficient of the provided Hlls, which is the
+// size of their intersection divided by the size of the smaller Hll.  The
+// result is between 0 and 1, and it is 0 if either Hll is empty.  It returns
+// ErrIncompatible if the Hlls don't have the same regwidth, log2m, and hasher
+// settings.
+//
+// The intersection is computed as described for IntersectionCardinality.  When
+// the smaller Hll is much smaller than the union, the error of the result can
+// be large.
+func Overlap(a, b Hll) (float64, error) {
+
+	pair, err := estimatePair(a, b)
+	if err != nil {
+		return 0, err
+	}
+
+	smaller := math.Min(pair.a, pair.b)
+	if smaller == 0 {
+		return 0, nil
+	}
+	return math.Min(1, pair.intersection/smaller), nil
+}
+
+// pairEstimate holds the unrounded cardinality estimates used by the pairwise
+// set operations.
+type pairEstimate struct {
+	a, b, union, intersection float64
+}
+
+// estimatePair estimates the cardinalities of a, b, and their union and
+// intersection using the estimator in the settings of a.  The intersection is
+// clamped to [0, min(|A|, |B|)] and is exact if both Hlls are explicit.
+func estimatePair(a, b Hll) (pairEstimate, error) {
+
+	hlls := []Hll{a, b}
+	if err := checkCompatible(hlls); err != nil {
+		return pairEstimate{}, err
+	}
+	a, b = hlls[0], hlls[1]
+
+	estimator := a.settings.estimator
+	pair := pairEstimate{
+		a: a.estimateCardinality(estimator),
+		b: b.estimateCardinality(estimator),
+	}
+
+	_, aExplicit := a.storage.(explicitStorage)
+	_, bExplicit := b.storage.(explicitStorage)
+	if aExplicit && bExplicit {
+		pair.intersection = float64(explicitIntersection(hlls))
+		pair.union = pair.a + pair.b - pair.intersection
+		return pair, nil
+	}
+
+	union := Hll{settings: a.settings}
+	union.union(a, false)
+	union.union(b, false)
+
+	pair.union = union.estimateCardinality(estimator)
+	pair.intersection = math.Max(0, math.Min(math.Min(pair.a, pair.b), pair.a+pair.b-pair.union))
+	return pair, nil
+}
+
+// checkCompatible initializes the Hlls and returns ErrIncompatible if they
+// don't all have the same settings as the first.
+func checkCompatible(hlls []Hll) error {
+	for i := range hlls {
+		hlls[i].initOrPanic()
+		if !hlls[0].compatible(hlls[i]) {
+			return ErrIncompatible
+		}
+	}
+	return nil
+}
+
+// explicitIntersection counts the values that are present in all of the Hlls,
+// which must use the explicit representation.
+func explicitIntersection(hlls []Hll) uint64 {
+
+	// iterate over the smallest set.
+	smallest := hlls[0].storage.(explicitStorage)
+	for _, hll := range hlls[1:] {
+		if s := hll.storage.(explicitStorage); len(s) < len(smallest) {
+			smallest = s
+		}
+	}
+
+	var count uint64
+	for value := range smallest {
+		inAll := true
+		for _, hll := range hlls {
+			if _, ok := hll.storage.(explicitStorage)[value]; !ok {
+				inAll = false
+				break
+			}
+		}
+		if inAll {
+			count++
+		}
+	}
+
+	return count
+}
+
+// inclusionExclusion sums the signed cardinalities of the unions of union with
+// every non-empty subset of hlls[start:].  depth is the number of Hlls that
+// have already been included in union.
+func inclusionExclusion(estimator Estimator, union Hll, hlls []Hll, start, depth int) float64 {
+
+	sum := 0.0
+	for i := start; i < len(hlls); i++ {
+
+		next := Hll{settings: union.settings}
+		next.union(union, false)
+		next.union(hlls[i], false)
+
+		// subsets with an odd number of Hlls are added and those with an even
+		// number are subtracted.
+		cardinality := next.estimateCardinality(estimator)
+		if depth%2 == 0 {
+			sum += cardinality
+		} else {
+			sum -= cardinality
+		}
+
+		sum += inclusionExclusion(estimator, next, hlls, i+1, depth+1)
+	}
+
+	return sum
+}
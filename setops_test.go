@@ -0,0 +1,321 @@
+package hll
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overlappingHlls returns Hlls that each contain the values [i*offset,
+// i*offset+n).
+func overlappingHlls(t *testing.T, settings Settings, count, n, offset int) []Hll {
+
+	r := rand.New(rand.NewSource(1234567890))
+	values := make([]uint64, (count-1)*offset+n)
+	for i := range values {
+		values[i] = r.Uint64()
+	}
+
+	hlls := make([]Hll, count)
+	for i := range hlls {
+		hlls[i] = newHll(t, settings)
+		for _, value := range values[i*offset : i*offset+n] {
+			hlls[i].AddRaw(value)
+		}
+	}
+	return hlls
+}
+
+func Test_IntersectionCardinality_Explicit(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	hlls := overlappingHlls(t, settings, 3, 50, 20)
+	for _, hll := range hlls {
+		assertExplicit(t, hll)
+	}
+
+	cardinality, err := IntersectionCardinality(hlls[0], hlls[1])
+	require.NoError(t, err)
+	assert.Equal(t, uint64(30), cardinality)
+
+	cardinality, err = IntersectionCardinalityAll(hlls...)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), cardinality)
+
+	// the inputs are unmodified.
+	assert.Equal(t, uint64(50), hlls[0].Cardinality())
+}
+
+func Test_IntersectionCardinality_Probabilistic(t *testing.T) {
+
+	settings := Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, tc := range []struct {
+		count, n, offset int
+	}{
+		{count: 2, n: 100000, offset: 50000},
+		{count: 2, n: 1000, offset: 500}, // sparse
+		{count: 3, n: 100000, offset: 25000},
+		{count: 2, n: 100000, offset: 100000}, // disjoint
+	} {
+		t.Run(fmt.Sprintf("%+v", tc), func(t *testing.T) {
+
+			hlls := overlappingHlls(t, settings, tc.count, tc.n, tc.offset)
+			expected := math.Max(0, float64(tc.n-(tc.count-1)*tc.offset))
+
+			cardinality, err := IntersectionCardinalityAll(hlls...)
+			require.NoError(t, err)
+
+			// the error is relative to the size of the union.
+			union := float64(tc.n + (tc.count-1)*tc.offset)
+			assert.InDelta(t, expected, float64(cardinality), 3*union*1.04/math.Sqrt(1<<14))
+			assert.True(t, cardinality <= uint64(tc.n))
+		})
+	}
+}
+
+func Test_IntersectionCardinality_Mixed(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	hlls := overlappingHlls(t, settings, 2, 10000, 9990)
+	small := newHll(t, settings)
+	for i := 0; i < 10; i++ {
+		small.AddRaw(uint64(i + 1))
+	}
+	assertExplicit(t, small)
+
+	// clamped to the size of the smaller set.
+	cardinality, err := IntersectionCardinality(hlls[0], small)
+	require.NoError(t, err)
+	assert.True(t, cardinality <= 10)
+
+	// empty
+	cardinality, err = IntersectionCardinality(hlls[0], newHll(t, settings))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), cardinality)
+
+	cardinality, err = IntersectionCardinalityAll()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), cardinality)
+
+	cardinality, err = IntersectionCardinalityAll(hlls[0])
+	require.NoError(t, err)
+	assert.Equal(t, hlls[0].Cardinality(), cardinality)
+}
+
+func Test_IntersectionCardinality_Errors(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	_, err := IntersectionCardinality(newHll(t, settings), newHll(t, Settings{Log2m: 12, Regwidth: 5}))
+	assert.Equal(t, ErrIncompatible, err)
+
+	hasherSettings := settings
+	hasherSettings.Hasher = fnvHasher{}
+	_, err = IntersectionCardinality(newHll(t, settings), newHll(t, hasherSettings))
+	assert.Equal(t, ErrIncompatible, err)
+
+	_, err = IntersectionCardinalityAll(make([]Hll, 17)...)
+	assert.Error(t, err)
+}
+
+func Test_Jaccard_Difference_Overlap(t *testing.T) {
+
+	for _, tc := range []struct {
+		name     string
+		settings Settings
+		n        int
+		exact    bool
+	}{
+		{name: "explicit", settings: Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, n: 40, exact: true},
+		{name: "dense", settings: Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, n: 100000},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+
+			// a has n values, b has n/2 values, half of which are in a.
+			r := rand.New(rand.NewSource(1234567890))
+			values := make([]uint64, 5*tc.n/4)
+			for i := range values {
+				values[i] = r.Uint64()
+			}
+
+			a, b := newHll(t, tc.settings), newHll(t, tc.settings)
+			for _, value := range values[:tc.n] {
+				a.AddRaw(value)
+			}
+			for _, value := range values[3*tc.n/4:] {
+				b.AddRaw(value)
+			}
+
+			delta := 0.0
+			if !tc.exact {
+				delta = 3 * 1.04 / math.Sqrt(1<<14)
+			}
+
+			jaccard, err := Jaccard(a, b)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.25/1.25, jaccard, delta)
+
+			overlap, err := Overlap(a, b)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.5, overlap, 2*delta)
+
+			difference, err := DifferenceCardinality(a, b)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.75*float64(tc.n), float64(difference), delta*1.25*float64(tc.n))
+
+			difference, err = DifferenceCardinality(b, a)
+			require.NoError(t, err)
+			assert.InDelta(t, 0.25*float64(tc.n), float64(difference), delta*1.25*float64(tc.n))
+
+			// identical sets.
+			jaccard, err = Jaccard(a, a)
+			require.NoError(t, err)
+			assert.InDelta(t, 1.0, jaccard, delta)
+
+			difference, err = DifferenceCardinality(a, a)
+			require.NoError(t, err)
+			assert.InDelta(t, 0, float64(difference), delta*float64(tc.n))
+		})
+	}
+}
+
+func Test_Jaccard_Difference_Overlap_Empty(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	empty := newHll(t, settings)
+	hll := newHll(t, settings)
+	hll.AddRaw(1234567890)
+
+	jaccard, err := Jaccard(empty, empty)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, jaccard)
+
+	overlap, err := Overlap(empty, hll)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, overlap)
+
+	difference, err := DifferenceCardinality(hll, empty)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), difference)
+
+	_, err = Jaccard(hll, newHll(t, Settings{Log2m: 12, Regwidth: 5}))
+	assert.Equal(t, ErrIncompatible, err)
+	_, err = Overlap(hll, newHll(t, Settings{Log2m: 12, Regwidth: 5}))
+	assert.Equal(t, ErrIncompatible, err)
+	_, err = DifferenceCardinality(hll, newHll(t, Settings{Log2m: 12, Regwidth: 5}))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+// Test_UnionAll ensures that UnionAll produces the same result as repeated
+// calls to StrictUnion.
+func Test_UnionAll(t *testing.T) {
+
+	// ensure that the parallel merge is exercised.
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	tests := []struct {
+		settings Settings
+		sizes    []int
+	}{
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, []int{0, 1, 2, 3}},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, []int{100, 100, 100}},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, []int{10, 100, 200, 300}},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, []int{10, 100, 10000}},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: false}, []int{10, 20, 30, 40}},
+		{Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false}, []int{10, 20}},
+		{Settings{Log2m: 16, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}, []int{10, 1000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000}},
+		{Settings{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false}, []int{100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000, 100000}},
+		{Settings{Log2m: 15, Regwidth: 3, ExplicitThreshold: 0, SparseEnabled: false}, []int{100000, 100000, 100000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%+v/%v", tt.settings, tt.sizes), func(t *testing.T) {
+
+			r := rand.New(rand.NewSource(1234567890))
+
+			var hlls []Hll
+			for _, size := range tt.sizes {
+				hll := newHll(t, tt.settings)
+				for i := 0; i < size; i++ {
+					hll.AddRaw(r.Uint64())
+				}
+				hlls = append(hlls, hll)
+			}
+			originals := make([][]byte, len(hlls))
+			for i := range hlls {
+				originals[i] = hlls[i].ToBytes()
+			}
+
+			expected := newHll(t, tt.settings)
+			for _, hll := range hlls {
+				require.NoError(t, expected.StrictUnion(hll))
+			}
+
+			union, err := UnionAll(hlls...)
+			require.NoError(t, err)
+			assert.Equal(t, reflect.TypeOf(expected.storage), reflect.TypeOf(union.storage))
+			assert.Equal(t, expected.ToBytes(), union.ToBytes())
+
+			for i := range hlls {
+				assert.Equal(t, originals[i], hlls[i].ToBytes())
+			}
+		})
+	}
+}
+
+func Test_UnionAll_Errors(t *testing.T) {
+
+	union, err := UnionAll()
+	require.NoError(t, err)
+	assert.Equal(t, Hll{}, union)
+
+	settings := Settings{Log2m: 11, Regwidth: 5}
+	_, err = UnionAll(newHll(t, settings), newHll(t, Settings{Log2m: 12, Regwidth: 5}))
+	assert.Equal(t, ErrIncompatible, err)
+}
+
+func BenchmarkUnionAll(b *testing.B) {
+	hlls := benchmarkUnionHlls(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = UnionAll(hlls...)
+	}
+}
+
+func BenchmarkUnion_Repeated(b *testing.B) {
+	hlls := benchmarkUnionHlls(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		union, _ := NewHll(hlls[0].Settings())
+		for _, hll := range hlls {
+			_ = union.StrictUnion(hll)
+		}
+	}
+}
+
+// benchmarkUnionHlls returns a mix of Hlls in each representation.
+func benchmarkUnionHlls(b *testing.B) []Hll {
+
+	settings := Settings{Log2m: 14, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	r := rand.New(rand.NewSource(1234567890))
+
+	var hlls []Hll
+	for i := 0; i < 1000; i++ {
+		hll, err := NewHll(settings)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 1<<uint(i%16); j++ {
+			hll.AddRaw(r.Uint64())
+		}
+		hlls = append(hlls, hll)
+	}
+	return hlls
+}
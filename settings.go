@@ -3,6 +3,7 @@ package hll
 import (
 	"fmt"
 	"math"
+	"math/bits"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -57,6 +58,25 @@ type Settings struct {
 	// representation.  The thresholds for conversion are automatically
 	// calculated by the library when this field is set to true (recommended).
 	SparseEnabled bool
+
+	// Hasher is used to hash values passed to AddBytes, AddString, AddInt64,
+	// and AddUint64.  If nil, the 64 bit variant of MurmurHash3 is used (see
+	// Murmur3Hasher).  Since the hasher is not part of the storage spec, Hlls
+	// deserialized with FromBytes always use the default.  StrictUnion will
+	// refuse to combine Hlls that declare different hashers, but UnionBytes
+	// can't check the hasher of the serialized Hll, so it's up to the caller
+	// to only pass it Hlls that were hashed like dst.  Because settings
+	// are cached by value, the Hasher must be comparable with ==, including any
+	// values that it holds in interface fields.  The cache is never evicted, so
+	// a Hasher that is a pointer should be created once and shared rather than
+	// created for each Hll.
+	Hasher Hasher
+
+	// Estimator is the algorithm used by Cardinality.  The zero value is
+	// ClassicEstimator, which matches the Java and PostgreSQL implementations.
+	// Like the Hasher, it is not part of the storage spec, so Hlls deserialized
+	// with FromBytes always use the default.
+	Estimator Estimator
 }
 
 var defaultSettings *settings
@@ -105,13 +125,23 @@ type settings struct {
 	explicitAuto, sparseEnabled        bool
 	explicitThreshold, sparseThreshold int
 
+	// hasher is the Hasher provided in the Settings, which may be nil.
+	hasher Hasher
+
+	// estimator is the Estimator used by Cardinality.
+	estimator Estimator
+
 	// pwMaxMask is a mask that prevents overflow of HyperLogLog registers.
 	pwMaxMask uint64
 
-	// mBitsMask is a precomputed mask where the bottom-most regwidth bits are
-	// set.
+	// mBitsMask is a precomputed mask where the bottom-most log2m bits are set.
+	// It extracts the register index from a raw value.
 	mBitsMask uint64
 
+	// regwidthMask is a precomputed mask where the bottom-most regwidth bits
+	// are set.  It is also the maximum register value.
+	regwidthMask uint64
+
 	// alpha * m^2 (the constant in the "'raw' HyperLogLog estimator")
 	alphaMSquared float64
 
@@ -169,10 +199,13 @@ func (s Settings) toInternal() (*settings, error) {
 		sparseThreshold:      sparseThreshold,
 		pwMaxMask:            pwMaxMask(regwidth),
 		mBitsMask:            uint64((1 << uint(log2m)) - 1),
+		regwidthMask:         uint64((1 << uint(regwidth)) - 1),
 		alphaMSquared:        alphaMSquared(log2m),
 		smallEstimatorCutoff: smallEstimatorCutoff(1 << uint(log2m)),
 		largeEstimatorCutoff: largeEstimatorCutoff(twoToL),
 		twoToL:               twoToL,
+		hasher:               s.Hasher,
+		estimator:            s.Estimator,
 	}
 
 	// install the settings.  note that if another equal set of settings had
@@ -206,15 +239,41 @@ func (s *Settings) validate() error {
 		return fmt.Errorf("ExplicitThreshold is too large.  Allows at most %d but got %d", maximumExpthreshParam, s.ExplicitThreshold)
 	}
 
+	if !isComparable(s.Hasher) {
+		return fmt.Errorf("Hasher must be a comparable type but got %T", s.Hasher)
+	}
+
+	if s.Estimator < 0 || s.Estimator >= numEstimators {
+		return fmt.Errorf("Estimator is invalid.  Got %v", s.Estimator)
+	}
+
 	return nil
 }
 
+// isComparable reports whether the Hasher can be used in a key of the settings
+// cache.  Checking that its type is comparable is insufficient since a struct
+// can hold an interface whose dynamic value is not, so it tries the insert
+// instead.
+func isComparable(hasher Hasher) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	_ = map[Hasher]struct{}{hasher: {}}
+
+	return true
+}
+
 // toExternal translates the internal settings back to their exported version.
 func (s *settings) toExternal() Settings {
 	settings := Settings{
 		Log2m:         s.log2m,
 		Regwidth:      s.regwidth,
 		SparseEnabled: s.sparseEnabled,
+		Hasher:        s.hasher,
+		Estimator:     s.estimator,
 	}
 
 	if s.explicitAuto {
@@ -226,6 +285,48 @@ func (s *settings) toExternal() Settings {
 	return settings
 }
 
+// register calculates the register index and value for the provided raw value.
+// A register value of 0 indicates that the value does not affect any register
+// and should be ignored.
+func (s *settings) register(value uint64) (int, byte) {
+
+	// following documentation courtesy of the java implementation:
+	//
+	// p(w): position of the least significant set bit (one-indexed)
+	// By contract: p(w) <= 2^(registerValueInBits) - 1 (the max register
+	// value)
+	//
+	// By construction of pwMaxMask,
+	//      lsb(pwMaxMask) = 2^(registerValueInBits) - 2,
+	// thus lsb(any_long | pwMaxMask) <= 2^(registerValueInBits) - 2,
+	// thus 1 + lsb(any_long | pwMaxMask) <= 2^(registerValueInBits) -1.
+	substreamValue := uint64(value >> uint(s.log2m))
+	if substreamValue == 0 {
+		// The paper does not cover p(0x0), so the special value 0 is used.
+		// 0 is the original initialization value of the registers, so by
+		// doing this the multiset simply ignores it. This is acceptable
+		// because the probability is 1/(2^(2^registerSizeInBits)).
+		return 0, 0
+	}
+
+	// NOTE : trailing zeros == the 0-based index of the least significant 1
+	//        bit.
+	pW := (byte)(1 + bits.TrailingZeros64(substreamValue|s.pwMaxMask))
+	// NOTE:  no +1 as in paper since 0-based indexing
+	i := int(value & s.mBitsMask)
+
+	return i, pW
+}
+
+// effectiveHasher returns the hasher used by the Hll, substituting the default
+// if none has been configured.
+func (s *settings) effectiveHasher() Hasher {
+	if s.hasher == nil {
+		return Murmur3Hasher
+	}
+	return s.hasher
+}
+
 // calculateExplicitThreshold determines a good cutoff to switch between
 // explicit and probabilistic storage.
 func calculateExplicitThreshold(log2m, regwidth int) int {
@@ -67,6 +67,33 @@ func Test_SettingsValidate(t *testing.T) {
 	}
 }
 
+func Test_SettingsValidate_Hasher(t *testing.T) {
+
+	settings := Settings{
+		Log2m:    11,
+		Regwidth: 5,
+		Hasher:   fnvHasher{},
+	}
+	require.NoError(t, settings.validate())
+
+	// func types can't be used as map keys, so they can't be cached.
+	settings.Hasher = funcHasher(func(data []byte) uint64 { return 0 })
+	err := settings.validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Hasher must be a comparable type")
+
+	// a comparable type can hold a value that isn't.
+	settings.Hasher = wrappedHasher{funcHasher(func(data []byte) uint64 { return 0 })}
+	err = settings.validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "Hasher must be a comparable type")
+
+	settings.Hasher = wrappedHasher{fnvHasher{}}
+	require.NoError(t, settings.validate())
+	_, err = NewHll(settings)
+	require.NoError(t, err)
+}
+
 func Test_Settings_calculateExplicitThreshold(t *testing.T) {
 	assert.Equal(t, 160, calculateExplicitThreshold(11, 5))
 	assert.Equal(t, 384, calculateExplicitThreshold(12, 6))
@@ -14,6 +14,40 @@ func (s sparseStorage) sizeInBytes(settings *settings) int {
 
 func (s sparseStorage) writeBytes(settings *settings, bytes []byte) {
 
+	addr := 0
+	bitsPerRegister := int(settings.log2m + settings.regwidth)
+
+	for _, reg := range s.sortedRegisters() {
+		writeBits(bytes, addr, (uint64(reg)<<uint(settings.regwidth))|uint64(s[reg]), bitsPerRegister)
+		addr += bitsPerRegister
+	}
+}
+
+// writeTo writes the same registers as writeBytes to the chunkWriter.  Every 8
+// registers end on a byte boundary, so they are written in groups of 8.
+func (s sparseStorage) writeTo(settings *settings, w *chunkWriter) {
+
+	bitsPerRegister := int(settings.log2m + settings.regwidth)
+	sortedRegisters := s.sortedRegisters()
+
+	for len(sortedRegisters) > 0 {
+		n := len(sortedRegisters)
+		if n > 8 {
+			n = 8
+		}
+
+		bytes := w.next(divideBy8RoundUp(n * bitsPerRegister))
+		for i, reg := range sortedRegisters[:n] {
+			writeBits(bytes, i*bitsPerRegister, (uint64(reg)<<uint(settings.regwidth))|uint64(s[reg]), bitsPerRegister)
+		}
+
+		sortedRegisters = sortedRegisters[n:]
+	}
+}
+
+// sortedRegisters returns the indices of the set registers in ascending order.
+func (s sparseStorage) sortedRegisters() []int32 {
+
 	// per the storage spec, the registers must be in sorted order.  i'm not
 	// sure if other implementations will complain if that's not the case, but
 	// better safe than sorry.
@@ -23,13 +57,7 @@ func (s sparseStorage) writeBytes(settings *settings, bytes []byte) {
 	}
 	sort.Slice(sortedRegisters, func(i, j int) bool { return sortedRegisters[i] < sortedRegisters[j] })
 
-	addr := 0
-	bitsPerRegister := int(settings.log2m + settings.regwidth)
-
-	for _, reg := range sortedRegisters {
-		writeBits(bytes, addr, (uint64(reg)<<uint(settings.regwidth))|uint64(s[reg]), bitsPerRegister)
-		addr += bitsPerRegister
-	}
+	return sortedRegisters
 }
 
 func (s sparseStorage) fromBytes(settings *settings, bytes []byte) error {
@@ -42,7 +70,12 @@ func (s sparseStorage) fromBytes(settings *settings, bytes []byte) error {
 
 	for i := 0; i < numRegisters; i++ {
 		regAndVal := readBits(bytes, i*bitsPerRegister, bitsPerRegister)
-		s[int32(regAndVal>>uint(settings.regwidth))] = byte(regAndVal) & regMask
+
+		// keep the largest value so that a corrupt payload that repeats a
+		// register decodes the same as it would be unioned by UnionBytes.
+		// this also ignores entries with a value of zero, which can appear in
+		// the padding at the end when registers are narrower than a byte.
+		s.setIfGreater(settings, int(regAndVal>>uint(settings.regwidth)), byte(regAndVal)&regMask)
 	}
 
 	return nil
@@ -78,3 +111,16 @@ func (s sparseStorage) indicator(settings *settings) (float64, int) {
 
 	return sum, numberOfZeros
 }
+
+func (s sparseStorage) histogram(settings *settings) []int {
+
+	counts := make([]int, 1<<uint(settings.regwidth))
+
+	// every register that isn't present in the map is zero.
+	counts[0] = (1 << uint(settings.log2m)) - len(s)
+	for _, v := range s {
+		counts[v]++
+	}
+
+	return clampHistogram(settings, counts)
+}
@@ -0,0 +1,72 @@
+package hll
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+)
+
+// errNullHll is returned when scanning a NULL column into an Hll.
+var errNullHll = errors.New("cannot scan NULL into Hll.  Use NullHll for nullable columns")
+
+// Scan implements sql.Scanner so that an Hll can be read directly from a
+// postgresql-hll column.  It accepts the raw storage spec bytes, as returned
+// for bytea columns or binary results, as well as the "\x" prefixed hex text
+// that the hll type produces in text mode.  The value is decoded as by
+// UnmarshalBinary, so the settings are taken from the serialized header.
+//
+// Scanning a NULL returns an error.  Use NullHll for nullable columns.
+func (h *Hll) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		return errNullHll
+	case []byte:
+		// the first byte of the binary format holds the version in its upper
+		// nibble, so it can never be confused with the leading backslash.
+		if len(src) > 0 && src[0] == '\\' {
+			return h.UnmarshalText(src)
+		}
+		return h.UnmarshalBinary(src)
+	case string:
+		return h.UnmarshalText([]byte(src))
+	default:
+		return fmt.Errorf("cannot scan %T into Hll", src)
+	}
+}
+
+// Value implements driver.Valuer.  The Hll is written as the storage spec
+// bytes, which PostgreSQL accepts for both hll and bytea parameters.
+func (h Hll) Value() (driver.Value, error) {
+	return h.MarshalBinary()
+}
+
+// NullHll is an Hll that may be NULL.  It implements sql.Scanner and
+// driver.Valuer in the same fashion as sql.NullString.
+type NullHll struct {
+	Hll   Hll
+	Valid bool // Valid is true if Hll is not NULL
+}
+
+// Scan implements sql.Scanner.
+func (n *NullHll) Scan(src interface{}) error {
+	if src == nil {
+		n.Hll, n.Valid = Hll{}, false
+		return nil
+	}
+
+	if err := n.Hll.Scan(src); err != nil {
+		n.Valid = false
+		return err
+	}
+
+	n.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (n NullHll) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Hll.Value()
+}
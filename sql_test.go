@@ -0,0 +1,191 @@
+package hll
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ sql.Scanner   = &Hll{}
+	_ driver.Valuer = Hll{}
+	_ sql.Scanner   = &NullHll{}
+	_ driver.Valuer = NullHll{}
+)
+
+func init() {
+	sql.Register("hlltest", &fakeDriver{})
+}
+
+func Test_SQL_RoundTrip(t *testing.T) {
+
+	db := openFakeDB(t)
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+
+	for _, size := range []int{0, 10, 100, 10000} {
+		h := newHll(t, settings)
+		for i := 0; i < size; i++ {
+			h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+		}
+
+		_, err := db.Exec("insert", h)
+		require.NoError(t, err)
+
+		// "binary" returns the bytea bytes and "text" returns the \x hex that
+		// the hll type produces in text mode, both as []byte and as a string.
+		for _, query := range []string{"binary", "text", "string"} {
+			var out Hll
+			require.NoError(t, db.QueryRow(query).Scan(&out), query)
+			assert.Equal(t, settings, out.Settings(), query)
+			assert.Equal(t, h.ToBytes(), out.ToBytes(), query)
+		}
+	}
+}
+
+func Test_SQL_Null(t *testing.T) {
+
+	db := openFakeDB(t)
+
+	h := newHll(t, Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	_, err := db.Exec("insert", NullHll{Hll: h, Valid: true})
+	require.NoError(t, err)
+
+	out := NullHll{}
+	require.NoError(t, db.QueryRow("binary").Scan(&out))
+	assert.True(t, out.Valid)
+	assert.Equal(t, h.ToBytes(), out.Hll.ToBytes())
+
+	_, err = db.Exec("insert", NullHll{})
+	require.NoError(t, err)
+
+	require.NoError(t, db.QueryRow("binary").Scan(&out))
+	assert.False(t, out.Valid)
+	assertEmpty(t, out.Hll)
+
+	var notNull Hll
+	assert.True(t, errors.Is(db.QueryRow("binary").Scan(&notNull), errNullHll))
+}
+
+func Test_SQL_Errors(t *testing.T) {
+
+	var h Hll
+	assert.Error(t, h.Scan(int64(1)))
+	assert.Error(t, h.Scan(`\x12zz`))
+	assert.Equal(t, ErrInsufficientBytes, h.Scan([]byte{0x12}))
+
+	var n NullHll
+	assert.Error(t, n.Scan([]byte{0x12}))
+	assert.False(t, n.Valid)
+
+	resetDefaults()
+	_, err := Hll{}.Value()
+	assert.Equal(t, errNoSettings, err)
+}
+
+func openFakeDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("hlltest", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeDriver is a database/sql driver that stores a single value.  Any Exec
+// replaces the value with its first argument, and a Query returns the value
+// in the form named by the query: "binary" as raw bytes, "text" as the \x hex
+// bytes, and "string" as the \x hex string.
+type fakeDriver struct {
+	mu    sync.Mutex
+	value driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{d}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{c.driver, query}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	driver *fakeDriver
+	query  string
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+	s.driver.value = args[0]
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.driver.mu.Lock()
+	defer s.driver.mu.Unlock()
+
+	value := s.driver.value
+	if bytes, ok := value.([]byte); ok {
+		var h Hll
+		if err := h.UnmarshalBinary(bytes); err != nil {
+			return nil, err
+		}
+		text, _ := h.MarshalText()
+
+		switch s.query {
+		case "text":
+			value = text
+		case "string":
+			value = string(text)
+		}
+	}
+
+	return &fakeRows{values: []driver.Value{value}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"hll"}
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
@@ -17,6 +17,10 @@ type storage interface {
 	// least as many bytes as indicated by sizeInBytes.
 	writeBytes(settings *settings, bytes []byte)
 
+	// writeTo serializes the storage to the chunkWriter.  The output is the same as writeBytes, but it is produced
+	// a chunk at a time so that the serialized value never has to be held in memory in its entirety.
+	writeTo(settings *settings, w *chunkWriter)
+
 	// fromBytes deserializes the provided byte slice into this storage object.  It will return an error in case the
 	// byte slice contains invalid information or is truncated.
 	fromBytes(settings *settings, bytes []byte) error
@@ -37,4 +41,8 @@ type registers interface {
 	//
 	// For reference, Z = indicator(2^(-M[j])) for all j from 0 -> num registers where M[j] is the register value.
 	indicator(settings *settings) (float64, int)
+
+	// histogram counts the number of registers having each possible value.  The returned slice is indexed by register
+	// value and has 2^regwidth entries.  Values above q+1 in Ertl's paper are counted as q+1 (see clampHistogram).
+	histogram(settings *settings) []int
 }
@@ -0,0 +1,215 @@
+package hll
+
+import (
+	"io"
+)
+
+// streamChunkSize is the size of the buffer used by WriteTo and ReadHll to
+// encode and decode the storage a chunk at a time.
+const streamChunkSize = 4096
+
+// WriteTo implements io.WriterTo.  It writes the same bytes as ToBytes, but the
+// storage is encoded a chunk at a time rather than into a buffer holding the
+// entire serialized Hll, which can be megabytes for dense Hlls with a large
+// log2m.
+func (h *Hll) WriteTo(w io.Writer) (int64, error) {
+
+	h.initOrPanic()
+
+	cw := chunkWriter{w: w, buf: make([]byte, 0, streamChunkSize)}
+
+	header := h.header()
+	copy(cw.next(3), header[:])
+
+	if h.storage != nil {
+		h.storage.writeTo(h.settings, &cw)
+	}
+
+	cw.flush()
+
+	return cw.n, cw.err
+}
+
+// ReadHll deserializes an Hll from the provided reader.  It is equivalent to
+// calling FromBytes on the entire contents of r, but the storage is decoded a
+// chunk at a time without first reading the contents into memory.
+//
+// Since the storage spec does not record the length of the explicit and sparse
+// representations, r is read until EOF.  To read several Hlls from the same
+// stream, they must be framed by the caller, e.g. by writing the length of each
+// one ahead of it and reading it back through an io.LimitReader.
+func ReadHll(r io.Reader) (Hll, error) {
+	h, _, err := readHll(r)
+	return h, err
+}
+
+// ReadFrom implements io.ReaderFrom.  It replaces the receiver with the Hll read
+// from r as ReadHll would.  The Hasher and Estimator are retained as described
+// by UnmarshalBinary.
+func (h *Hll) ReadFrom(r io.Reader) (int64, error) {
+
+	decoded, n, err := readHll(r)
+	if err != nil {
+		return n, err
+	}
+
+	return n, h.replace(decoded)
+}
+
+// readHll implements ReadHll and additionally returns the number of bytes read.
+func readHll(r io.Reader) (Hll, int64, error) {
+
+	cr := chunkReader{r: r}
+
+	header, err := cr.next(make([]byte, 3))
+	if err != nil {
+		return Hll{}, cr.n, err
+	}
+	if len(header) < 3 {
+		return Hll{}, cr.n, ErrInsufficientBytes
+	}
+
+	storageType, settings, err := parseHeader(header)
+	if err != nil {
+		return Hll{}, cr.n, err
+	}
+
+	h := Hll{settings: settings}
+
+	switch storageType {
+	case explicit:
+		h.storage = make(explicitStorage)
+		err = cr.readAll(settings, h.storage, streamChunkSize)
+	case sparse:
+		// every 8 registers end on a byte boundary, so a chunk holding a
+		// multiple of 8 registers can be decoded on its own.
+		bitsPerRegister := settings.log2m + settings.regwidth
+		h.storage = make(sparseStorage)
+		err = cr.readAll(settings, h.storage, streamChunkSize-streamChunkSize%bitsPerRegister)
+	case dense:
+		h.storage, err = cr.readDense(settings)
+	}
+
+	if err != nil {
+		return Hll{}, cr.n, err
+	}
+
+	return h, cr.n, nil
+}
+
+// chunkWriter buffers serialized storage and writes it to the underlying writer
+// whenever the buffer fills up.  Errors are sticky, so callers need only check
+// err after the final flush.
+type chunkWriter struct {
+	w   io.Writer
+	buf []byte
+	n   int64
+	err error
+}
+
+// next returns the next size zeroed bytes of the buffer, flushing first if they
+// would not fit.  size must not exceed the capacity of the buffer.
+func (c *chunkWriter) next(size int) []byte {
+
+	if len(c.buf)+size > cap(c.buf) {
+		c.flush()
+	}
+
+	start := len(c.buf)
+	c.buf = growZeroed(c.buf, size)
+
+	return c.buf[start:]
+}
+
+// flush writes the buffered bytes to the underlying writer.
+func (c *chunkWriter) flush() {
+
+	if len(c.buf) > 0 && c.err == nil {
+		n, err := c.w.Write(c.buf)
+		c.n += int64(n)
+		c.err = err
+	}
+
+	c.buf = c.buf[:0]
+}
+
+// chunkReader reads serialized storage a buffer at a time and counts the bytes
+// read.
+type chunkReader struct {
+	r io.Reader
+	n int64
+}
+
+// next fills buf from the underlying reader and returns the portion that was
+// filled.  The result is shorter than buf only at EOF.
+func (c *chunkReader) next(buf []byte) ([]byte, error) {
+
+	n, err := io.ReadFull(c.r, buf)
+	c.n += int64(n)
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+
+	return buf[:n], err
+}
+
+// readAll decodes chunks of the provided size into the storage until EOF.  The
+// chunk size must be such that each chunk can be decoded on its own.
+func (c *chunkReader) readAll(settings *settings, s storage, chunkSize int) error {
+
+	buf := make([]byte, chunkSize)
+	for {
+		chunk, err := c.next(buf)
+		if err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := s.fromBytes(settings, chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// readDense decodes the register values into a new dense storage.  The storage
+// grows as the registers are read so that a header alone can't force a large
+// allocation.  Like denseStorage.fromBytes, it returns ErrInsufficientBytes
+// unless the remainder of the stream holds exactly the expected number of
+// bytes.
+func (c *chunkReader) readDense(settings *settings) (denseStorage, error) {
+
+	var s denseStorage
+
+	remaining := denseSizeInBytes(settings)
+	buf := make([]byte, streamChunkSize)
+
+	for remaining > 0 {
+		if remaining < len(buf) {
+			buf = buf[:remaining]
+		}
+		chunk, err := c.next(buf)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) < len(buf) {
+			return nil, ErrInsufficientBytes
+		}
+
+		word := len(s)
+		s = append(s, make(denseStorage, divideBy8RoundUp(len(chunk)))...)
+		s.readWords(word, chunk)
+		remaining -= len(chunk)
+	}
+
+	trailing, err := c.next(buf[:1])
+	if err != nil {
+		return nil, err
+	}
+	if len(trailing) > 0 {
+		return nil, ErrInsufficientBytes
+	}
+
+	return s, nil
+}
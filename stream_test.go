@@ -0,0 +1,173 @@
+package hll
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ io.WriterTo   = &Hll{}
+	_ io.ReaderFrom = &Hll{}
+)
+
+// streamSettings covers every storage type along with sparse registers that
+// don't align with bytes and dense storage that spans several chunks.
+var streamSettings = []Settings{
+	{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+	{Log2m: 4, Regwidth: 8, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true},
+	{Log2m: 13, Regwidth: 3, ExplicitThreshold: 0, SparseEnabled: true},
+	{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false},
+}
+
+func Test_WriteTo_ReadHll(t *testing.T) {
+
+	for _, settings := range streamSettings {
+		for _, size := range []int{0, 1, 10, 100, 1000, 100000} {
+			t.Run(fmt.Sprintf("%+v/%d", settings, size), func(t *testing.T) {
+
+				r := rand.New(rand.NewSource(int64(size)))
+
+				h := newHll(t, settings)
+				for i := 0; i < size; i++ {
+					h.AddRaw(r.Uint64())
+				}
+				expected := h.ToBytes()
+
+				var buf bytes.Buffer
+				n, err := h.WriteTo(&buf)
+				require.NoError(t, err)
+				assert.Equal(t, int64(len(expected)), n)
+				assert.Equal(t, expected, buf.Bytes())
+
+				decoded, err := ReadHll(bytes.NewReader(expected))
+				require.NoError(t, err)
+				assert.Equal(t, expected, decoded.ToBytes())
+
+				// short reads must not affect the result.
+				decoded, err = ReadHll(iotest.OneByteReader(bytes.NewReader(expected)))
+				require.NoError(t, err)
+				assert.Equal(t, expected, decoded.ToBytes())
+
+				var readFrom Hll
+				n, err = readFrom.ReadFrom(bytes.NewReader(expected))
+				require.NoError(t, err)
+				assert.Equal(t, int64(len(expected)), n)
+				assert.Equal(t, expected, readFrom.ToBytes())
+			})
+		}
+	}
+}
+
+func Test_AppendBytes(t *testing.T) {
+
+	for _, settings := range streamSettings {
+		h := newHll(t, settings)
+		for i := 0; i < 1000; i++ {
+			h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+		}
+		expected := h.ToBytes()
+
+		prefix := []byte("prefix")
+		assert.Equal(t, append(prefix, expected...), h.AppendBytes(prefix))
+
+		// a buffer with spare capacity holding stale bytes is reused.
+		dirty := append(make([]byte, 0, len(prefix)+len(expected)+10), prefix...)
+		full := dirty[:cap(dirty)]
+		for i := len(prefix); i < len(full); i++ {
+			full[i] = 0xff
+		}
+		appended := h.AppendBytes(dirty)
+		assert.Equal(t, append(prefix, expected...), appended)
+		assert.Equal(t, &full[0], &appended[0], "expected the buffer to be reused")
+	}
+}
+
+func Test_ReadHll_Errors(t *testing.T) {
+
+	for _, settings := range streamSettings {
+		h := newHll(t, settings)
+		for i := 0; i < 1000; i++ {
+			h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+		}
+		data := h.ToBytes()
+
+		_, expectedErr := FromBytes(data[:len(data)-1])
+		_, err := ReadHll(iotest.HalfReader(bytes.NewReader(data[:len(data)-1])))
+		assert.Equal(t, expectedErr, err)
+	}
+
+	_, err := ReadHll(bytes.NewReader(nil))
+	assert.Equal(t, ErrInsufficientBytes, err)
+	_, err = ReadHll(bytes.NewReader([]byte{0x11, 0x8b}))
+	assert.Equal(t, ErrInsufficientBytes, err)
+	_, err = ReadHll(bytes.NewReader([]byte{0x21, 0x8b, 0x7f}))
+	assert.Error(t, err)
+
+	// trailing bytes after dense registers are rejected like FromBytes.
+	dense := newHll(t, Settings{Log2m: 4, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false})
+	dense.AddRaw(0x9e3779b97f4a7c15)
+	_, err = ReadHll(bytes.NewReader(append(dense.ToBytes(), 0)))
+	assert.Equal(t, ErrInsufficientBytes, err)
+
+	// errors from the reader are returned as-is.
+	readErr := errors.New("read failed")
+	_, err = ReadHll(io.MultiReader(bytes.NewReader(dense.ToBytes()[:5]), iotest.ErrReader(readErr)))
+	assert.Equal(t, readErr, err)
+}
+
+func Test_WriteTo_Errors(t *testing.T) {
+
+	h := newHll(t, Settings{Log2m: 16, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	n, err := h.WriteTo(&limitedWriter{remaining: streamChunkSize + 10})
+	assert.Equal(t, io.ErrShortWrite, err)
+	assert.Equal(t, int64(streamChunkSize+10), n)
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	h, _ := NewHll(Settings{Log2m: 20, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = h.WriteTo(io.Discard)
+	}
+}
+
+func BenchmarkToBytes(b *testing.B) {
+	h, _ := NewHll(Settings{Log2m: 20, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: false})
+	h.AddRaw(0x9e3779b97f4a7c15)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = h.ToBytes()
+	}
+}
+
+// limitedWriter accepts a fixed number of bytes and fails thereafter.
+type limitedWriter struct {
+	remaining int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > w.remaining {
+		n := w.remaining
+		w.remaining = 0
+		return n, io.ErrShortWrite
+	}
+	w.remaining -= len(p)
+	return len(p), nil
+}
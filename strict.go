@@ -0,0 +1,169 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrRegisterOutOfRange is returned by FromBytesStrict when a sparse register
+// has a value of zero or when any register has a value larger than a 64 bit
+// hash can produce.
+var ErrRegisterOutOfRange = errors.New("register is out of range")
+
+// ErrUnsortedExplicit is returned by FromBytesStrict when the explicit values
+// are not in strictly ascending order, which includes duplicate values.
+var ErrUnsortedExplicit = errors.New("explicit values are not sorted or contain duplicates")
+
+// ErrUnsortedSparse is returned by FromBytesStrict when the sparse registers
+// are not in strictly ascending order, which includes duplicate registers.
+var ErrUnsortedSparse = errors.New("sparse registers are not sorted or contain duplicates")
+
+// ErrTrailingBytes is returned by FromBytesStrict when there are bytes beyond
+// the end of the serialized Hll.
+var ErrTrailingBytes = errors.New("trailing bytes after serialized Hll")
+
+// ErrNonZeroPadding is returned by FromBytesStrict when the unused bit of the
+// header or the unused bits after the final sparse register are not zero.
+var ErrNonZeroPadding = errors.New("non-zero padding bits in serialized Hll")
+
+// FromBytesStrict deserializes the provided byte slice into an Hll like
+// FromBytes, but it additionally rejects any input that would not have been
+// produced by a conforming implementation.  In addition to the errors returned
+// by FromBytes, it returns:
+//
+//   - ErrUnsortedExplicit if the explicit values are not strictly ascending.
+//   - ErrUnsortedSparse if the sparse registers are not strictly ascending.
+//   - ErrRegisterOutOfRange if a sparse register has a value of zero or if any
+//     register value exceeds 65-log2m.
+//   - ErrTrailingBytes if there are whole bytes after the final register or
+//     after the header of an empty Hll.
+//   - ErrNonZeroPadding if the unused bit of the header or any of the unused
+//     bits after the final sparse register are set.
+//
+// It is intended for input that can't be trusted, such as data from a
+// warehouse that may contain corrupt rows.
+func FromBytesStrict(bytes []byte) (Hll, error) {
+
+	storageType, settings, err := parseHeader(bytes)
+	if err != nil {
+		return Hll{}, err
+	}
+
+	// the most significant bit of the cutoff byte is unused.
+	if bytes[2]&0x80 != 0 {
+		return Hll{}, ErrNonZeroPadding
+	}
+
+	payload := bytes[3:]
+
+	switch storageType {
+	case empty:
+		if len(payload) > 0 {
+			return Hll{}, ErrTrailingBytes
+		}
+	case explicit:
+		err = validateExplicitBytes(payload)
+	case sparse:
+		err = validateSparseBytes(settings, payload)
+	case dense:
+		err = validateDenseBytes(settings, payload)
+	}
+
+	if err != nil {
+		return Hll{}, err
+	}
+
+	return FromBytes(bytes)
+}
+
+// validateExplicitBytes ensures that the serialized explicit values are sorted
+// as signed 64 bit integers per the storage spec.
+func validateExplicitBytes(bytes []byte) error {
+
+	if len(bytes)%8 != 0 {
+		return ErrInsufficientBytes
+	}
+
+	for i := 8; i < len(bytes); i += 8 {
+		prev := int64(binary.BigEndian.Uint64(bytes[i-8:]))
+		if int64(binary.BigEndian.Uint64(bytes[i:])) <= prev {
+			return ErrUnsortedExplicit
+		}
+	}
+
+	return nil
+}
+
+// validateSparseBytes ensures that the serialized sparse registers are sorted,
+// in range, and followed by no more than the padding required to fill out the
+// final byte.
+func validateSparseBytes(settings *settings, bytes []byte) error {
+
+	bitsPerRegister := settings.log2m + settings.regwidth
+
+	numRegisters := (8 * len(bytes)) / bitsPerRegister
+
+	// when registers are narrower than a byte, the padding of the final byte
+	// can hold whole registers.  those are made up of zero bits.
+	for numRegisters > 0 && divideBy8RoundUp((numRegisters-1)*bitsPerRegister) == len(bytes) &&
+		readBits(bytes, (numRegisters-1)*bitsPerRegister, bitsPerRegister) == 0 {
+		numRegisters--
+	}
+
+	if divideBy8RoundUp(numRegisters*bitsPerRegister) < len(bytes) {
+		return ErrTrailingBytes
+	}
+
+	if paddingBits := 8*len(bytes) - numRegisters*bitsPerRegister; paddingBits > 0 {
+		if readBits(bytes, numRegisters*bitsPerRegister, paddingBits) != 0 {
+			return ErrNonZeroPadding
+		}
+	}
+
+	// the index field is log2m bits wide, so every index is in range.
+	maxValue := maxRegisterValue(settings)
+	prev := int64(-1)
+	for i := 0; i < numRegisters; i++ {
+		regAndVal := readBits(bytes, i*bitsPerRegister, bitsPerRegister)
+		index, value := regAndVal>>uint(settings.regwidth), regAndVal&settings.regwidthMask
+		if value == 0 || value > maxValue {
+			return ErrRegisterOutOfRange
+		}
+		if int64(index) <= prev {
+			return ErrUnsortedSparse
+		}
+		prev = int64(index)
+	}
+
+	return nil
+}
+
+// validateDenseBytes ensures that the serialized dense registers are exactly
+// the expected length and in range.  Since there are at least 16 registers, the
+// registers always end on a byte boundary and there are no padding bits.
+func validateDenseBytes(settings *settings, bytes []byte) error {
+
+	size := denseSizeInBytes(settings)
+
+	if len(bytes) < size {
+		return ErrInsufficientBytes
+	} else if len(bytes) > size {
+		return ErrTrailingBytes
+	}
+
+	maxValue := maxRegisterValue(settings)
+	for i := 0; i < 1<<uint(settings.log2m); i++ {
+		if readBits(bytes, i*settings.regwidth, settings.regwidth) > maxValue {
+			return ErrRegisterOutOfRange
+		}
+	}
+
+	return nil
+}
+
+// maxRegisterValue is the largest register value that the estimators accept,
+// which is q+1 in Ertl's paper.  A hash has 64-log2m bits after the register
+// index, so larger values can only come from corrupt input.
+func maxRegisterValue(settings *settings) uint64 {
+	return uint64(ertlQ(settings) + 1)
+}
@@ -0,0 +1,131 @@
+package hll
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_FromBytesStrict(t *testing.T) {
+
+	// narrow sparse registers leave room for a whole register in the padding.
+	settings := append([]Settings{{Log2m: 4, Regwidth: 2, ExplicitThreshold: 0, SparseEnabled: true}}, streamSettings...)
+
+	for _, settings := range settings {
+		for _, size := range []int{0, 1, 2, 3, 10, 100, 1000, 10000} {
+			t.Run(fmt.Sprintf("%+v/%d", settings, size), func(t *testing.T) {
+				h := newHll(t, settings)
+				for i := 0; i < size; i++ {
+					h.AddRaw(uint64(i+1) * 0x9e3779b97f4a7c15)
+				}
+				bytes := h.ToBytes()
+
+				strict, err := FromBytesStrict(bytes)
+				require.NoError(t, err)
+				assert.Equal(t, bytes, strict.ToBytes())
+				assert.Equal(t, registerValues(h), registerValues(strict))
+			})
+		}
+	}
+}
+
+// Test_FromBytes_SparsePadding ensures that a zero entry in the padding of
+// narrow sparse registers doesn't overwrite register 0.
+func Test_FromBytes_SparsePadding(t *testing.T) {
+
+	h := newHll(t, Settings{Log2m: 4, Regwidth: 2, ExplicitThreshold: 0, SparseEnabled: true})
+	// 3 registers of 6 bits occupy 18 bits, so the 6 bits of padding in the
+	// final byte decode as a 4th register with index 0 and value 0.
+	h.storage = sparseStorage{0: 3, 5: 1, 9: 2}
+	bytes := h.ToBytes()
+	require.Len(t, bytes, 3+3)
+
+	decoded, err := FromBytes(bytes)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{0: 3, 5: 1, 9: 2}, decoded.storage)
+
+	decoded, err = FromBytesStrict(bytes)
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{0: 3, 5: 1, 9: 2}, decoded.storage)
+
+	// 8 registers of 6 bits fill 6 bytes exactly, as would 7 registers and
+	// padding, so the final register must not be mistaken for padding.
+	h.storage = sparseStorage{0: 3, 3: 1, 4: 3, 5: 2, 6: 1, 7: 1, 12: 3, 13: 3}
+	decoded, err = FromBytesStrict(h.ToBytes())
+	require.NoError(t, err)
+	assert.Equal(t, h.storage, decoded.storage)
+}
+
+func Test_FromBytesStrict_Errors(t *testing.T) {
+
+	settings := Settings{Log2m: 11, Regwidth: 5, ExplicitThreshold: AutoExplicitThreshold, SparseEnabled: true}
+	h := newHll(t, settings)
+	header := h.ToBytes()
+
+	explicitBytes := func(values ...int64) []byte {
+		bytes := append([]byte{0x12}, header[1:]...)
+		for _, value := range values {
+			bytes = append(bytes, make([]byte, 8)...)
+			binary.BigEndian.PutUint64(bytes[len(bytes)-8:], uint64(value))
+		}
+		return bytes
+	}
+
+	// each register is 16 bits: an 11 bit index and 5 bit value.
+	sparseBytes := func(extra int, registers ...[2]uint64) []byte {
+		bytes := append([]byte{0x13}, header[1:]...)
+		bytes = append(bytes, make([]byte, 2*len(registers)+extra)...)
+		for i, reg := range registers {
+			writeBits(bytes[3:], i*16, reg[0]<<5|reg[1], 16)
+		}
+		return bytes
+	}
+
+	denseSize := divideBy8RoundUp(2048 * 5)
+	denseBytes := append(append([]byte{0x14}, header[1:]...), make([]byte, denseSize)...)
+
+	// with a log2m of 11, a 6 bit register can hold values up to 63, but the
+	// estimators only accept values up to 54.  the sparse register is index 5
+	// with a value of 63 followed by 7 bits of padding.
+	denseWideBytes := append([]byte{0x14, 0xab, 0x7f}, make([]byte, divideBy8RoundUp(2048*6))...)
+	denseWideBytes[3] = 63 << 2
+
+	tests := []struct {
+		label string
+		bytes []byte
+		err   error
+	}{
+		{"empty trailing", append(header, 0), ErrTrailingBytes},
+		{"header padding", []byte{0x11, header[1], header[2] | 0x80}, ErrNonZeroPadding},
+		{"explicit unsorted", explicitBytes(1, 3, 2), ErrUnsortedExplicit},
+		{"explicit duplicate", explicitBytes(1, 2, 2), ErrUnsortedExplicit},
+		{"explicit signed order", explicitBytes(1, -1), ErrUnsortedExplicit},
+		{"explicit truncated", explicitBytes(1, 2)[:18], ErrInsufficientBytes},
+		{"sparse unsorted", sparseBytes(0, [2]uint64{5, 1}, [2]uint64{3, 1}), ErrUnsortedSparse},
+		{"sparse duplicate", sparseBytes(0, [2]uint64{5, 1}, [2]uint64{5, 2}), ErrUnsortedSparse},
+		{"sparse zero value", sparseBytes(0, [2]uint64{5, 1}, [2]uint64{6, 0}), ErrRegisterOutOfRange},
+		{"sparse trailing", sparseBytes(1, [2]uint64{5, 1}), ErrTrailingBytes},
+		{"dense trailing", append(denseBytes, 0), ErrTrailingBytes},
+		{"dense truncated", denseBytes[:len(denseBytes)-1], ErrInsufficientBytes},
+		{"sparse wide value", []byte{0x13, 0xab, 0x7f, 0x00, 0xbf, 0x80}, ErrRegisterOutOfRange},
+		{"dense wide value", denseWideBytes, ErrRegisterOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			_, err := FromBytesStrict(tt.bytes)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	// sparse padding only exists when the registers don't end on a byte.
+	narrow := newHll(t, Settings{Log2m: 4, Regwidth: 2, ExplicitThreshold: 0, SparseEnabled: true})
+	narrow.storage = sparseStorage{3: 1}
+	bytes := narrow.ToBytes()
+	bytes[len(bytes)-1] |= 0x01
+	_, err := FromBytesStrict(bytes)
+	assert.Equal(t, ErrNonZeroPadding, err)
+}
@@ -0,0 +1,60 @@
+// Golden writes golden.csv.gz, which contains HLL sketches serialized by
+// Apache DataSketches itself for Test_DataSketchesGolden, along with
+// golden.txt, which records the versions that produced them.  The sketches
+// cover the same lgConfigK, types, forms, and counts as sketches.csv.gz, which
+// gen_datasketches.go writes, so they hold the strings "0" through "n-1" added
+// with update(String).
+//
+// From this directory, with the datasketches-java 3.x and datasketches-memory
+// jars from Maven Central:
+//
+//	java -cp datasketches-java-3.3.0.jar:datasketches-memory-2.2.0.jar Golden.java
+import java.io.FileOutputStream;
+import java.io.IOException;
+import java.io.OutputStreamWriter;
+import java.io.PrintWriter;
+import java.nio.charset.StandardCharsets;
+import java.util.zip.GZIPOutputStream;
+
+import org.apache.datasketches.hll.HllSketch;
+import org.apache.datasketches.hll.TgtHllType;
+
+public class Golden {
+
+  public static void main(String[] args) throws IOException {
+
+    try (PrintWriter w = new PrintWriter(new OutputStreamWriter(
+        new GZIPOutputStream(new FileOutputStream("golden.csv.gz")), StandardCharsets.UTF_8))) {
+
+      w.print("lg_k,type,compact,count,sketch\n");
+
+      for (int lgK : new int[] {4, 8, 12}) {
+        for (TgtHllType type : new TgtHllType[] {TgtHllType.HLL_4, TgtHllType.HLL_6, TgtHllType.HLL_8}) {
+          for (boolean compact : new boolean[] {true, false}) {
+            for (int n : new int[] {0, 1, 7, 8, 24, 25, 100, 1000, 100000}) {
+              HllSketch sketch = new HllSketch(lgK, type);
+              for (int i = 0; i < n; i++) {
+                sketch.update(Integer.toString(i));
+              }
+              byte[] bytes = compact ? sketch.toCompactByteArray() : sketch.toUpdatableByteArray();
+              w.print(lgK + "," + type + "," + compact + "," + n + ",\\x" + hex(bytes) + "\n");
+            }
+          }
+        }
+      }
+    }
+
+    try (PrintWriter w = new PrintWriter("golden.txt", "UTF-8")) {
+      w.print("datasketches-java " + HllSketch.class.getPackage().getImplementationVersion() + "\n");
+      w.print("java " + System.getProperty("java.version") + "\n");
+    }
+  }
+
+  private static String hex(byte[] bytes) {
+    StringBuilder sb = new StringBuilder();
+    for (byte b : bytes) {
+      sb.append(String.format("%02x", b));
+    }
+    return sb.toString();
+  }
+}
@@ -0,0 +1,58 @@
+// Golden writes golden.csv.gz, which contains HyperLogLog++ sketches
+// serialized by ZetaSketch itself for Test_ZetaSketchGolden, along with
+// golden.txt, which records the versions that produced them.  Each sketch holds
+// the strings "0" through "n-1", which ZetaSketch hashes with Fingerprint2011
+// like BigQuery's HLL_COUNT.INIT does for STRING values.  The sparse precision
+// is the normal precision plus 5, capped at 25, which is what ToZetaSketch
+// writes.
+//
+// From this directory, with zetasketch 0.1.0 and its dependencies from Maven
+// Central on the class path:
+//
+//	java -cp zetasketch-0.1.0.jar:protobuf-java-3.6.1.jar:fastutil-8.2.2.jar Golden.java
+import java.io.FileOutputStream;
+import java.io.IOException;
+import java.io.OutputStreamWriter;
+import java.io.PrintWriter;
+import java.nio.charset.StandardCharsets;
+import java.util.zip.GZIPOutputStream;
+
+import com.google.zetasketch.HyperLogLogPlusPlus;
+
+public class Golden {
+
+  public static void main(String[] args) throws IOException {
+
+    try (PrintWriter w = new PrintWriter(new OutputStreamWriter(
+        new GZIPOutputStream(new FileOutputStream("golden.csv.gz")), StandardCharsets.UTF_8))) {
+
+      w.print("precision,count,sketch\n");
+
+      for (int precision : new int[] {10, 12, 15}) {
+        for (int n : new int[] {0, 1, 2, 10, 100, 300, 2000, 5000, 100000}) {
+          HyperLogLogPlusPlus<String> sketch = new HyperLogLogPlusPlus.Builder()
+              .normalPrecision(precision)
+              .sparsePrecision(Math.min(precision + 5, 25))
+              .buildForStrings();
+          for (int i = 0; i < n; i++) {
+            sketch.add(Integer.toString(i));
+          }
+          w.print(precision + "," + n + ",\\x" + hex(sketch.serializeToByteArray()) + "\n");
+        }
+      }
+    }
+
+    try (PrintWriter w = new PrintWriter("golden.txt", "UTF-8")) {
+      w.print("zetasketch " + HyperLogLogPlusPlus.class.getPackage().getImplementationVersion() + "\n");
+      w.print("java " + System.getProperty("java.version") + "\n");
+    }
+  }
+
+  private static String hex(byte[] bytes) {
+    StringBuilder sb = new StringBuilder();
+    for (byte b : bytes) {
+      sb.append(String.format("%02x", b));
+    }
+    return sb.toString();
+  }
+}
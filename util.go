@@ -8,6 +8,63 @@ func divideBy8RoundUp(i int) int {
 	return result
 }
 
+// growZeroed extends bytes by n zeroed bytes.  Like append, it reallocates only
+// if the capacity is insufficient and then grows the capacity proportionally,
+// so that repeated calls take amortized linear time.
+func growZeroed(bytes []byte, n int) []byte {
+	return append(bytes, make([]byte, n)...)
+}
+
+// getLSB6 reads a 6 bit value from an array of values that are packed starting
+// from the least significant bit of each byte, as in the dense encodings of
+// Redis and DataSketches.
+func getLSB6(bytes []byte, index int) byte {
+
+	pos := index * 6
+	b, shift := pos/8, uint(pos%8)
+
+	value := uint(bytes[b]) >> shift
+	if b+1 < len(bytes) {
+		value |= uint(bytes[b+1]) << (8 - shift)
+	}
+
+	return byte(value & 0x3f)
+}
+
+// setLSB6 writes a 6 bit value to a zeroed array packed like getLSB6, clamping
+// values that do not fit in 6 bits.
+func setLSB6(bytes []byte, index int, value byte) {
+
+	if value > 0x3f {
+		value = 0x3f
+	}
+
+	pos := index * 6
+	b, shift := pos/8, uint(pos%8)
+
+	bytes[b] |= value << shift
+	if b+1 < len(bytes) {
+		bytes[b+1] |= byte(uint(value) >> (8 - shift))
+	}
+}
+
+// appendUint32LE appends the little endian encoding of value to bytes.
+func appendUint32LE(bytes []byte, value uint32) []byte {
+	return append(bytes, byte(value), byte(value>>8), byte(value>>16), byte(value>>24))
+}
+
+// appendUint64LE appends the little endian encoding of value to bytes.
+func appendUint64LE(bytes []byte, value uint64) []byte {
+	return appendUint32LE(appendUint32LE(bytes, uint32(value)), uint32(value>>32))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
 // readBits reads nBits from the provided address in the byte array and returns
 // them as the LSB of a uint64.  The address is the 0-indexed bit position where
 // 0 equates to the MSB in the 0th byte, 63 is be the LSB in the 0th byte, 64 is
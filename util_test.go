@@ -18,6 +18,27 @@ func Test_divideBy8RoundUp(t *testing.T) {
 	assert.Equal(t, 9, divideBy8RoundUp(65))
 }
 
+func Test_growZeroed(t *testing.T) {
+
+	// spare capacity is reused and zeroed.
+	bytes := []byte{1, 2, 3, 4}[:1]
+	bytes = growZeroed(bytes, 2)
+	assert.Equal(t, []byte{1, 0, 0}, bytes)
+	assert.Equal(t, 4, cap(bytes))
+
+	// repeated growth reallocates a logarithmic number of times.
+	reallocations := 0
+	for i := 0; i < 10000; i++ {
+		previous := cap(bytes)
+		bytes = growZeroed(bytes, 3)
+		if cap(bytes) != previous {
+			reallocations++
+		}
+	}
+	assert.Equal(t, 30003, len(bytes))
+	assert.True(t, reallocations < 50, "%d reallocations", reallocations)
+}
+
 func Test_readWriteBits(t *testing.T) {
 
 	numSamples := 1000
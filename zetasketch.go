@@ -0,0 +1,457 @@
+package hll
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+// BigQuery's HLL_COUNT.INIT and HLL_COUNT.MERGE_PARTIAL produce HyperLogLog++
+// sketches in the format of ZetaSketch (https://github.com/google/zetasketch).
+// A sketch is an AggregatorStateProto whose hyperloglogplus_unique_state
+// extension is a HyperLogLogPlusUniqueStateProto.
+//
+// HyperLogLog++ takes the register index from the highest bits of the hash and
+// the register value from the number of leading zeros of the bits that follow,
+// which is the storage spec's scheme applied to the hash with its bits
+// reversed.  Registers are therefore mapped by reversing the bits of their
+// indexes, and a sketch of hashes h is equivalent to an Hll populated by
+// AddRaw(bits.Reverse64(h)).  That keeps the register values meaningful when
+// the Hll is folded to a smaller log2m or unioned with other imported sketches.
+//
+// Small sketches use a sparse representation with more index bits (the sparse
+// precision), which allows near exact counts.  Each sparse value holds the
+// highest bits of a hash, so it's imported as an explicit value with those bits
+// and exported from the highest bits of the reversed explicit values.
+
+const (
+	// HYPERLOGLOG_PLUS_UNIQUE in AggregatorType.
+	zetaSketchType            = 112
+	zetaSketchEncodingVersion = 2
+
+	zetaSketchMinPrecision       = 10
+	zetaSketchMaxPrecision       = 24
+	zetaSketchMaxSparsePrecision = 25
+
+	// zetaSketchSparsePrecisionDelta is the difference between the default
+	// sparse and normal precisions.
+	zetaSketchSparsePrecisionDelta = 5
+
+	// the sparse representation is converted to the normal one when its data
+	// exceeds 3/4 of the size of the normal data.
+	zetaSketchMaxSparseDataNumerator   = 3
+	zetaSketchMaxSparseDataDenominator = 4
+
+	// the number of bits of the register value in a sparse value that is
+	// flagged as such.
+	zetaSketchRhoWBits = 6
+
+	// AggregatorStateProto fields.
+	zetaSketchTypeField            = 1
+	zetaSketchNumValuesField       = 2
+	zetaSketchEncodingVersionField = 3
+	zetaSketchStateField           = 112
+
+	// HyperLogLogPlusUniqueStateProto fields.
+	zetaSketchSparseSizeField      = 2
+	zetaSketchPrecisionField       = 3
+	zetaSketchSparsePrecisionField = 4
+	zetaSketchDataField            = 5
+	zetaSketchSparseDataField      = 6
+
+	// protobuf wire types.
+	protoVarint          = 0
+	protoFixed64         = 1
+	protoLengthDelimited = 2
+	protoFixed32         = 5
+)
+
+// errZetaSketchInvalid is returned by FromZetaSketch when the sketch isn't a
+// valid HyperLogLog++ sketch.
+var errZetaSketchInvalid = errors.New("invalid ZetaSketch HyperLogLog++ sketch")
+
+// ZetaSketchSettings returns the settings of the Hlls returned by
+// FromZetaSketch for a sketch with the provided normal precision.  The explicit
+// threshold is 2^(precision-1), limited to 131,072, which keeps the values of
+// most sparse sketches exactly.
+func ZetaSketchSettings(precision int) Settings {
+
+	explicitThreshold := 1 << uint(precision-1)
+	if explicitThreshold > maximumExplicitThreshold {
+		explicitThreshold = maximumExplicitThreshold
+	}
+
+	return Settings{
+		Log2m:             precision,
+		Regwidth:          6,
+		ExplicitThreshold: explicitThreshold,
+		SparseEnabled:     true,
+	}
+}
+
+// zetaSketchState is a decoded HyperLogLogPlusUniqueStateProto.
+type zetaSketchState struct {
+	precision       int
+	sparsePrecision int
+	data            []byte
+	sparseData      []uint32
+}
+
+// FromZetaSketch converts a serialized ZetaSketch HyperLogLog++ sketch to an
+// Hll with ZetaSketchSettings for the sketch's normal precision, which becomes
+// the log2m.
+//
+// A sketch in the normal representation becomes a sparse or dense Hll with the
+// same registers.  A sketch in the sparse representation becomes an explicit
+// Hll with one value per sparse value, which retains the sparse precision as
+// long as there are no more values than the explicit threshold.  Beyond that,
+// the values are added to the registers, and the count has the accuracy of the
+// normal precision.  The number of values and value type of the sketch are
+// ignored.
+//
+// Note that BigQuery hashes values with a different function than the ones
+// provided by this package, so a value added to both counts twice in a union.
+func FromZetaSketch(data []byte) (Hll, error) {
+
+	var state []byte
+	err := readProtoFields(data, func(number, wireType int, value uint64, bytes []byte) error {
+		switch {
+		case number == zetaSketchTypeField && wireType == protoVarint:
+			if value != zetaSketchType {
+				return fmt.Errorf("not a ZetaSketch HyperLogLog++ sketch: type %d", value)
+			}
+		case number == zetaSketchEncodingVersionField && wireType == protoVarint:
+			if value != zetaSketchEncodingVersion {
+				return fmt.Errorf("unsupported ZetaSketch encoding version: %d", value)
+			}
+		case number == zetaSketchStateField && wireType == protoLengthDelimited:
+			state = bytes
+		}
+		return nil
+	})
+	if err != nil {
+		return Hll{}, err
+	}
+	if state == nil {
+		return Hll{}, errZetaSketchInvalid
+	}
+
+	s, err := readZetaSketchState(state)
+	if err != nil {
+		return Hll{}, err
+	}
+
+	settings, err := ZetaSketchSettings(s.precision).toInternal()
+	if err != nil {
+		return Hll{}, err
+	}
+
+	h := Hll{settings: settings}
+
+	if s.data != nil {
+		if len(s.data) != 1<<uint(s.precision) {
+			return Hll{}, ErrInsufficientBytes
+		}
+		for index, value := range s.data {
+			if uint64(value) > settings.regwidthMask {
+				return Hll{}, errZetaSketchInvalid
+			}
+			h.setRegister(reverseIndex(index, s.precision), value)
+		}
+		if h.storage != nil && h.storage.overCapacity(settings) {
+			h.upgrade()
+		}
+	}
+
+	for _, sparseValue := range s.sparseData {
+		hash, err := s.decodeSparse(sparseValue)
+		if err != nil {
+			return Hll{}, err
+		}
+		h.AddRaw(bits.Reverse64(hash))
+	}
+
+	return h, nil
+}
+
+// readZetaSketchState decodes and validates a HyperLogLogPlusUniqueStateProto.
+func readZetaSketchState(state []byte) (zetaSketchState, error) {
+
+	var s zetaSketchState
+	var sparseDeltas []uint32
+
+	err := readProtoFields(state, func(number, wireType int, value uint64, bytes []byte) error {
+		switch {
+		case number == zetaSketchPrecisionField && wireType == protoVarint:
+			s.precision = int(int32(value))
+		case number == zetaSketchSparsePrecisionField && wireType == protoVarint:
+			s.sparsePrecision = int(int32(value))
+		case number == zetaSketchDataField && wireType == protoLengthDelimited:
+			s.data = bytes
+		case number == zetaSketchSparseDataField && wireType == protoLengthDelimited:
+			// sparse_data is optional bytes holding a sequence of varints.
+			for len(bytes) > 0 {
+				delta, n := binary.Uvarint(bytes)
+				if n <= 0 {
+					return errZetaSketchInvalid
+				}
+				sparseDeltas = append(sparseDeltas, uint32(delta))
+				bytes = bytes[n:]
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return s, err
+	}
+
+	if s.precision < zetaSketchMinPrecision || s.precision > zetaSketchMaxPrecision {
+		return s, fmt.Errorf("invalid ZetaSketch precision: %d", s.precision)
+	}
+	if s.sparsePrecision != 0 && (s.sparsePrecision < s.precision || s.sparsePrecision > zetaSketchMaxSparsePrecision) {
+		return s, fmt.Errorf("invalid ZetaSketch sparse precision: %d", s.sparsePrecision)
+	}
+	if len(sparseDeltas) > 0 && s.sparsePrecision == 0 {
+		return s, errZetaSketchInvalid
+	}
+
+	// the sparse values are sorted and difference encoded.
+	var sparseValue uint32
+	for _, delta := range sparseDeltas {
+		sparseValue += delta
+		s.sparseData = append(s.sparseData, sparseValue)
+	}
+
+	return s, nil
+}
+
+// rhoEncodedFlag is the bit that's set in sparse values that hold the normal
+// index and register value rather than the sparse index.
+func (s zetaSketchState) rhoEncodedFlag() uint32 {
+	return 1 << uint(maxInt(s.sparsePrecision, s.precision+zetaSketchRhoWBits))
+}
+
+// encodeSparse returns the sparse value of the hash.  It's the sparse index
+// unless the bits of the sparse index beyond the normal index are all zero, in
+// which case the register value can't be derived from it.  Then, it's the
+// normal index and the register value at the sparse precision with the
+// rhoEncodedFlag.
+func (s zetaSketchState) encodeSparse(hash uint64) uint32 {
+
+	sparseIndex := uint32(hash >> uint(64-s.sparsePrecision))
+	if sparseIndex&(1<<uint(s.sparsePrecision-s.precision)-1) != 0 {
+		return sparseIndex
+	}
+
+	normalIndex := sparseIndex >> uint(s.sparsePrecision-s.precision)
+	rhoW := bits.LeadingZeros64(hash<<uint(s.sparsePrecision)|1<<uint(s.sparsePrecision-1)) + 1
+
+	return s.rhoEncodedFlag() | normalIndex<<zetaSketchRhoWBits | uint32(rhoW)
+}
+
+// decodeSparse returns the smallest hash whose sparse value is the one
+// provided, which has the same registers as any such hash.
+func (s zetaSketchState) decodeSparse(sparseValue uint32) (uint64, error) {
+
+	flag := s.rhoEncodedFlag()
+
+	if sparseValue&flag == 0 {
+		if sparseValue >= 1<<uint(s.sparsePrecision) {
+			return 0, errZetaSketchInvalid
+		}
+		return uint64(sparseValue) << uint(64-s.sparsePrecision), nil
+	}
+
+	normalIndex := (sparseValue ^ flag) >> zetaSketchRhoWBits
+	rhoW := int(sparseValue & (1<<zetaSketchRhoWBits - 1))
+	if normalIndex >= 1<<uint(s.precision) || rhoW == 0 || rhoW > 65-s.sparsePrecision {
+		return 0, errZetaSketchInvalid
+	}
+
+	hash := uint64(normalIndex) << uint(64-s.precision)
+	if rhoW <= 64-s.sparsePrecision {
+		hash |= 1 << uint(64-s.sparsePrecision-rhoW)
+	}
+
+	return hash, nil
+}
+
+// ToZetaSketch converts the Hll to a ZetaSketch HyperLogLog++ sketch, which
+// BigQuery accepts in HLL_COUNT.MERGE and HLL_COUNT.EXTRACT.  The normal
+// precision is the Hll's log2m, which must be between 10 and 24, and the sparse
+// precision is 5 more, limited to 25, as in BigQuery's default.
+//
+// An explicit Hll becomes a sketch in the sparse representation with the
+// highest bits of its reversed values, as long as that's smaller than the
+// normal representation, which holds the Hll's registers.  The number of values
+// in the sketch is the Hll's cardinality, and the sketch has no value type.
+func (h *Hll) ToZetaSketch() ([]byte, error) {
+
+	h.initOrPanic()
+
+	s := zetaSketchState{precision: h.settings.log2m}
+	if s.precision < zetaSketchMinPrecision || s.precision > zetaSketchMaxPrecision {
+		return nil, fmt.Errorf("cannot convert Log2m %d to ZetaSketch.  Must be between %d and %d", s.precision, zetaSketchMinPrecision, zetaSketchMaxPrecision)
+	}
+
+	s.sparsePrecision = s.precision + zetaSketchSparsePrecisionDelta
+	if s.sparsePrecision > zetaSketchMaxSparsePrecision {
+		s.sparsePrecision = zetaSketchMaxSparsePrecision
+	}
+
+	var sparseData []byte
+	sparse := false
+
+	if explicit, ok := h.storage.(explicitStorage); ok {
+		s.sparseData = s.sparseValues(explicit)
+
+		var prev uint32
+		for _, sparseValue := range s.sparseData {
+			sparseData = appendProtoVarint(sparseData, uint64(sparseValue-prev))
+			prev = sparseValue
+		}
+
+		maxSparseData := zetaSketchMaxSparseDataNumerator << uint(s.precision) / zetaSketchMaxSparseDataDenominator
+		sparse = len(sparseData) <= maxSparseData
+	}
+
+	// fields are written in order of their numbers like the Java
+	// implementation, and an empty sketch only has the precisions.
+	var state []byte
+	if sparse && len(s.sparseData) > 0 {
+		state = appendProtoVarintField(state, zetaSketchSparseSizeField, uint64(len(s.sparseData)))
+	}
+	state = appendProtoVarintField(state, zetaSketchPrecisionField, uint64(s.precision))
+	state = appendProtoVarintField(state, zetaSketchSparsePrecisionField, uint64(s.sparsePrecision))
+
+	if sparse {
+		if len(s.sparseData) > 0 {
+			state = appendProtoBytesField(state, zetaSketchSparseDataField, sparseData)
+		}
+	} else if registers, empty := h.convertedRegisters(s.precision, 6); !empty {
+		data := make([]byte, len(registers))
+		for index := range data {
+			data[index] = registers[reverseIndex(index, s.precision)]
+		}
+		state = appendProtoBytesField(state, zetaSketchDataField, data)
+	}
+
+	sketch := appendProtoVarintField(nil, zetaSketchTypeField, zetaSketchType)
+	sketch = appendProtoVarintField(sketch, zetaSketchNumValuesField, h.Cardinality())
+	sketch = appendProtoVarintField(sketch, zetaSketchEncodingVersionField, zetaSketchEncodingVersion)
+	sketch = appendProtoBytesField(sketch, zetaSketchStateField, state)
+
+	return sketch, nil
+}
+
+// sparseValues returns the sorted sparse values of the reversed explicit
+// values.  Like ZetaSketch, only the largest register value is kept for values
+// that hold the normal index.
+func (s zetaSketchState) sparseValues(explicit explicitStorage) []uint32 {
+
+	flag := s.rhoEncodedFlag()
+
+	unique := make(map[uint32]uint32, len(explicit))
+	for value := range explicit {
+		sparseValue := s.encodeSparse(bits.Reverse64(value))
+
+		// flagged values are keyed by the flag and normal index, which can't
+		// collide with a sparse index since those are below the flag.
+		key := sparseValue
+		if sparseValue&flag != 0 {
+			key = sparseValue &^ (1<<zetaSketchRhoWBits - 1)
+		}
+		if sparseValue > unique[key] {
+			unique[key] = sparseValue
+		}
+	}
+
+	sparseValues := make([]uint32, 0, len(unique))
+	for _, sparseValue := range unique {
+		sparseValues = append(sparseValues, sparseValue)
+	}
+	sort.Slice(sparseValues, func(i, j int) bool { return sparseValues[i] < sparseValues[j] })
+
+	return sparseValues
+}
+
+// reverseIndex maps between the register indexes of HyperLogLog++ and the
+// storage spec by reversing the low log2m bits.
+func reverseIndex(index, log2m int) int {
+	return int(bits.Reverse32(uint32(index)) >> uint(32-log2m))
+}
+
+// readProtoFields calls fn for each field of the serialized protobuf message.
+// The value is provided for varint and fixed width fields, and the bytes are
+// provided for length delimited fields.  Groups are not supported.
+func readProtoFields(data []byte, fn func(number, wireType int, value uint64, bytes []byte) error) error {
+
+	for len(data) > 0 {
+
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errZetaSketchInvalid
+		}
+		data = data[n:]
+
+		number, wireType := int(key>>3), int(key&0x7)
+
+		var value uint64
+		var bytes []byte
+
+		switch wireType {
+		case protoVarint:
+			value, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errZetaSketchInvalid
+			}
+		case protoFixed64:
+			if len(data) < 8 {
+				return ErrInsufficientBytes
+			}
+			value, n = binary.LittleEndian.Uint64(data), 8
+		case protoLengthDelimited:
+			length, m := binary.Uvarint(data)
+			if m <= 0 {
+				return errZetaSketchInvalid
+			}
+			if length > uint64(len(data)-m) {
+				return ErrInsufficientBytes
+			}
+			bytes = data[m : m+int(length)]
+			n = m + int(length)
+		case protoFixed32:
+			if len(data) < 4 {
+				return ErrInsufficientBytes
+			}
+			value, n = uint64(binary.LittleEndian.Uint32(data)), 4
+		default:
+			return fmt.Errorf("unsupported protobuf wire type: %d", wireType)
+		}
+		data = data[n:]
+
+		if err := fn(number, wireType, value, bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendProtoVarint(data []byte, value uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(data, buf[:binary.PutUvarint(buf[:], value)]...)
+}
+
+func appendProtoVarintField(data []byte, number int, value uint64) []byte {
+	data = appendProtoVarint(data, uint64(number)<<3|protoVarint)
+	return appendProtoVarint(data, value)
+}
+
+func appendProtoBytesField(data []byte, number int, bytes []byte) []byte {
+	data = appendProtoVarint(data, uint64(number)<<3|protoLengthDelimited)
+	data = appendProtoVarint(data, uint64(len(bytes)))
+	return append(data, bytes...)
+}
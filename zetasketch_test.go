@@ -0,0 +1,325 @@
+package hll
+
+import (
+	"encoding/hex"
+	"math"
+	"math/bits"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitMix64 returns the i-th output of SplitMix64 with a seed of 0, which
+// stands in for the hashes of the values in the ZetaSketch fixtures.
+func splitMix64(i int) uint64 {
+	z := uint64(i+1) * 0x9e3779b97f4a7c15
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// zetaSketchStateOf returns the HyperLogLogPlusUniqueStateProto in a sketch.
+// The other fields aren't compared since the number of values in the fixtures
+// is the number of hashes added rather than the estimated cardinality.
+func zetaSketchStateOf(t *testing.T, sketch []byte) []byte {
+	var state []byte
+	err := readProtoFields(sketch, func(number, wireType int, value uint64, bytes []byte) error {
+		if number == zetaSketchStateField {
+			state = bytes
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	return state
+}
+
+//go:generate go run gen_zetasketch.go
+
+// Test_ZetaSketchFixtures runs through the sketches in
+// testdata/zetasketch/sketches.csv.gz.  Each line contains the precision, the
+// number of hashes, and the hex of the sketch after adding the first hashes
+// from splitMix64.  The sketches are generated by gen_zetasketch.go, a port of
+// the sparse and normal encodings in com.google.zetasketch.internal.hllplus,
+// and they cover both representations at precisions of 10, 12, and 15.
+func Test_ZetaSketchFixtures(t *testing.T) {
+
+	readCSV(t, "testdata/zetasketch/sketches.csv.gz", func(parts []string, lineNo int) {
+
+		require.Equal(t, 3, len(parts), "required 3 columns at line %d", lineNo)
+		require.True(t, strings.HasPrefix(parts[2], `\x`), "missing \\x at line %d", lineNo)
+
+		precision, err := strconv.Atoi(parts[0])
+		require.NoError(t, err, "invalid precision at line %d", lineNo)
+		count, err := strconv.Atoi(parts[1])
+		require.NoError(t, err, "invalid count at line %d", lineNo)
+		expected, err := hex.DecodeString(parts[2][2:])
+		require.NoError(t, err, "invalid hex at line %d", lineNo)
+
+		h := newHll(t, ZetaSketchSettings(precision))
+		for i := 0; i < count; i++ {
+			h.AddRaw(bits.Reverse64(splitMix64(i)))
+		}
+
+		actual, err := h.ToZetaSketch()
+		require.NoError(t, err)
+		require.Equal(t, zetaSketchStateOf(t, expected), zetaSketchStateOf(t, actual), "incorrect sketch at line %d", lineNo)
+
+		decoded, err := FromZetaSketch(expected)
+		require.NoError(t, err, "failed to convert line %d", lineNo)
+		require.Equal(t, registerValues(h), registerValues(decoded), "incorrect registers at line %d", lineNo)
+
+		actual, err = decoded.ToZetaSketch()
+		require.NoError(t, err)
+		require.Equal(t, zetaSketchStateOf(t, expected), zetaSketchStateOf(t, actual), "incorrect round trip at line %d", lineNo)
+	})
+}
+
+// Test_ZetaSketchGolden runs through the sketches in
+// testdata/zetasketch/golden.csv.gz, which are serialized by ZetaSketch itself.
+// testdata/zetasketch/Golden.java writes them and records the version of the
+// library that it ran with in golden.txt.  Since the values are hashed with
+// Fingerprint2011, which this package doesn't implement, the sketches are
+// checked by their estimates and round trips rather than against Hlls built
+// here.
+func Test_ZetaSketchGolden(t *testing.T) {
+
+	const path = "testdata/zetasketch/golden.csv.gz"
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		t.Skipf("%s has not been generated by testdata/zetasketch/Golden.java", path)
+	}
+
+	// the normal data of a sketch, which is nil in the sparse representation.
+	dataOf := func(sketch []byte) []byte {
+		var data []byte
+		err := readProtoFields(zetaSketchStateOf(t, sketch), func(number, wireType int, value uint64, bytes []byte) error {
+			if number == zetaSketchDataField {
+				data = bytes
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return data
+	}
+
+	readCSV(t, path, func(parts []string, lineNo int) {
+
+		require.Equal(t, 3, len(parts), "required 3 columns at line %d", lineNo)
+		require.True(t, strings.HasPrefix(parts[2], `\x`), "missing \\x at line %d", lineNo)
+
+		precision, err := strconv.Atoi(parts[0])
+		require.NoError(t, err, "invalid precision at line %d", lineNo)
+		count, err := strconv.Atoi(parts[1])
+		require.NoError(t, err, "invalid count at line %d", lineNo)
+		sketch, err := hex.DecodeString(parts[2][2:])
+		require.NoError(t, err, "invalid hex at line %d", lineNo)
+
+		decoded, err := FromZetaSketch(sketch)
+		require.NoError(t, err, "failed to convert line %d", lineNo)
+		require.Equal(t, precision, decoded.settings.log2m, "incorrect precision at line %d", lineNo)
+
+		// within three standard errors of the HyperLogLog estimate.
+		tolerance := 3 * 1.04 / math.Sqrt(float64(int(1)<<uint(precision)))
+		if count == 0 {
+			assertEmpty(t, decoded)
+		} else {
+			actual := float64(decoded.Cardinality())
+			assert.InEpsilon(t, float64(count), actual, tolerance, "inaccurate estimate at line %d", lineNo)
+		}
+
+		exported, err := decoded.ToZetaSketch()
+		require.NoError(t, err)
+		reimported, err := FromZetaSketch(exported)
+		require.NoError(t, err)
+		require.Equal(t, decoded.ToBytes(), reimported.ToBytes(), "incorrect round trip at line %d", lineNo)
+
+		// the normal representation has a single encoding.
+		if data := dataOf(sketch); data != nil {
+			require.Equal(t, data, dataOf(exported), "incorrect registers at line %d", lineNo)
+		}
+	})
+}
+
+func Test_FromZetaSketch(t *testing.T) {
+
+	// sparse values at a precision of 10 and sparse precision of 15: sparse
+	// index 3, and normal index 5 with a register value of 2 at the sparse
+	// precision, flagged by bit 16.
+	state := appendProtoVarintField(nil, zetaSketchSparseSizeField, 2)
+	state = appendProtoVarintField(state, zetaSketchPrecisionField, 10)
+	state = appendProtoVarintField(state, zetaSketchSparsePrecisionField, 15)
+	state = appendProtoBytesField(state, zetaSketchSparseDataField, []byte{0x03, 0xbf, 0x82, 0x04})
+
+	sketch := appendProtoVarintField(nil, zetaSketchTypeField, zetaSketchType)
+	sketch = appendProtoVarintField(sketch, zetaSketchNumValuesField, 2)
+	sketch = appendProtoVarintField(sketch, zetaSketchEncodingVersionField, zetaSketchEncodingVersion)
+	sketch = appendProtoBytesField(sketch, zetaSketchStateField, state)
+
+	h, err := FromZetaSketch(sketch)
+	require.NoError(t, err)
+	assert.Equal(t, explicitStorage{
+		bits.Reverse64(3 << 49):       {},
+		bits.Reverse64(5<<54 | 1<<47): {},
+	}, h.storage)
+
+	actual, err := h.ToZetaSketch()
+	require.NoError(t, err)
+	assert.Equal(t, sketch, actual)
+
+	// unknown fields are ignored.
+	unknown := appendProtoVarintField(nil, zetaSketchPrecisionField, 10)
+	unknown = appendProtoVarintField(unknown, zetaSketchSparsePrecisionField, 15)
+	unknown = appendProtoBytesField(unknown, zetaSketchSparseDataField, appendProtoVarint(appendProtoVarint(nil, 3), (1<<16|5<<6|2)-3))
+	unknown = appendProtoBytesField(unknown, 99, []byte("ignored"))
+
+	h, err = FromZetaSketch(appendProtoBytesField(nil, zetaSketchStateField, unknown))
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(h.storage.(explicitStorage)))
+
+	// normal data has a register per byte, indexed by the highest bits of the
+	// hash, so the indexes are reversed.
+	data := make([]byte, 1024)
+	data[1], data[512], data[1023] = 3, 1, 55
+	normal := appendProtoVarintField(nil, zetaSketchPrecisionField, 10)
+	normal = appendProtoVarintField(normal, zetaSketchSparsePrecisionField, 15)
+	normal = appendProtoBytesField(normal, zetaSketchDataField, data)
+
+	h, err = FromZetaSketch(appendProtoBytesField(nil, zetaSketchStateField, normal))
+	require.NoError(t, err)
+	assert.Equal(t, sparseStorage{512: 3, 1: 1, 1023: 55}, h.storage)
+
+	actual, err = h.ToZetaSketch()
+	require.NoError(t, err)
+	assert.Equal(t, normal, zetaSketchStateOf(t, actual))
+}
+
+func Test_ToZetaSketch(t *testing.T) {
+
+	// an empty Hll only has the precisions.
+	h := newHll(t, ZetaSketchSettings(14))
+	actual, err := h.ToZetaSketch()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x08, 0x70, 0x10, 0x00, 0x18, 0x02, 0x82, 0x07, 0x04, 0x18, 0x0e, 0x20, 0x13}, actual)
+
+	// at a precision of 15 and sparse precision of 20, the flag is bit 21, so
+	// the flagged value for normal index 1 without its register value equals
+	// sparse index 2^15+1.  both values must be kept.
+	h = newHll(t, ZetaSketchSettings(15))
+	h.AddRaw(bits.Reverse64(1<<59 | 1<<44))
+	h.AddRaw(bits.Reverse64(1<<49 | 1<<41))
+	actual, err = h.ToZetaSketch()
+	require.NoError(t, err)
+	decoded, err := FromZetaSketch(actual)
+	require.NoError(t, err)
+	assert.Equal(t, h.storage, decoded.storage)
+
+	// the sparse precision is limited to 25.
+	h = newHll(t, ZetaSketchSettings(22))
+	h.AddRaw(1)
+	actual, err = h.ToZetaSketch()
+	require.NoError(t, err)
+	state, err := readZetaSketchState(zetaSketchStateOf(t, actual))
+	require.NoError(t, err)
+	assert.Equal(t, 25, state.sparsePrecision)
+
+	// register storage converts to the normal representation regardless of the
+	// settings, and other regwidths are converted.
+	values := make([]uint64, 1000)
+	for i := range values {
+		values[i] = bits.Reverse64(splitMix64(i))
+	}
+
+	expected := newHll(t, Settings{Log2m: 11, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	expected.AddRawBatch(values)
+	expectedBytes, err := expected.ToZetaSketch()
+	require.NoError(t, err)
+
+	for _, settings := range []Settings{
+		{Log2m: 11, Regwidth: 5, ExplicitThreshold: 0, SparseEnabled: false},
+		{Log2m: 11, Regwidth: 8, ExplicitThreshold: 0, SparseEnabled: true},
+	} {
+		h := newHll(t, settings)
+		h.AddRawBatch(values)
+
+		actual, err := h.ToZetaSketch()
+		require.NoError(t, err)
+		assert.Equal(t, zetaSketchStateOf(t, expectedBytes), zetaSketchStateOf(t, actual), "%+v", settings)
+	}
+
+	// explicit values fall back to the normal representation when the sparse
+	// one would be larger than 3/4 of it.
+	h = newHll(t, Settings{Log2m: 10, Regwidth: 6, ExplicitThreshold: 1024, SparseEnabled: true})
+	h.AddRawBatch(values)
+	require.IsType(t, explicitStorage{}, h.storage)
+	actual, err = h.ToZetaSketch()
+	require.NoError(t, err)
+
+	expected = newHll(t, Settings{Log2m: 10, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+	expected.AddRawBatch(values)
+	expectedBytes, err = expected.ToZetaSketch()
+	require.NoError(t, err)
+	assert.Equal(t, zetaSketchStateOf(t, expectedBytes), zetaSketchStateOf(t, actual))
+
+	for _, log2m := range []int{9, 25} {
+		h := newHll(t, Settings{Log2m: log2m, Regwidth: 6, ExplicitThreshold: 0, SparseEnabled: true})
+		_, err := h.ToZetaSketch()
+		assert.Error(t, err, "log2m %d", log2m)
+	}
+}
+
+func Test_FromZetaSketch_Errors(t *testing.T) {
+
+	state := func(fields ...[]byte) []byte {
+		var bytes []byte
+		for _, field := range fields {
+			bytes = append(bytes, field...)
+		}
+		return appendProtoBytesField(nil, zetaSketchStateField, bytes)
+	}
+	precision := appendProtoVarintField(nil, zetaSketchPrecisionField, 10)
+	sparsePrecision := appendProtoVarintField(nil, zetaSketchSparsePrecisionField, 15)
+	sparseData := func(value uint64) []byte {
+		return appendProtoBytesField(nil, zetaSketchSparseDataField, appendProtoVarint(nil, value))
+	}
+
+	tests := []struct {
+		label string
+		bytes []byte
+		err   error
+	}{
+		{"no state", appendProtoVarintField(nil, zetaSketchTypeField, zetaSketchType), errZetaSketchInvalid},
+		{"truncated key", []byte{0x80}, errZetaSketchInvalid},
+		{"truncated state", state(precision)[:3], ErrInsufficientBytes},
+		{"truncated fixed64", []byte{0x09, 0, 0, 0}, ErrInsufficientBytes},
+		{"short data", state(precision, appendProtoBytesField(nil, zetaSketchDataField, make([]byte, 1023))), ErrInsufficientBytes},
+		{"wide register", state(precision, appendProtoBytesField(nil, zetaSketchDataField, append(make([]byte, 1023), 64))), errZetaSketchInvalid},
+		{"no sparse precision", state(precision, sparseData(1)), errZetaSketchInvalid},
+		{"sparse index", state(precision, sparsePrecision, sparseData(1<<15)), errZetaSketchInvalid},
+		{"sparse zero rhoW", state(precision, sparsePrecision, sparseData(1<<16)), errZetaSketchInvalid},
+		{"sparse wide rhoW", state(precision, sparsePrecision, sparseData(1<<16|51)), errZetaSketchInvalid},
+		{"sparse data truncated", state(precision, sparsePrecision, appendProtoBytesField(nil, zetaSketchSparseDataField, []byte{0x80})), errZetaSketchInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			_, err := FromZetaSketch(tt.bytes)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	for _, bytes := range [][]byte{
+		appendProtoVarintField(nil, zetaSketchTypeField, 100),
+		appendProtoVarintField(nil, zetaSketchEncodingVersionField, 1),
+		state(appendProtoVarintField(nil, zetaSketchPrecisionField, 9)),
+		state(appendProtoVarintField(nil, zetaSketchPrecisionField, 25)),
+		state(precision, appendProtoVarintField(nil, zetaSketchSparsePrecisionField, 9)),
+		state(precision, appendProtoVarintField(nil, zetaSketchSparsePrecisionField, 26)),
+		{0x0b}, // groups are not supported.
+	} {
+		_, err := FromZetaSketch(bytes)
+		assert.Error(t, err, "%x", bytes)
+	}
+}